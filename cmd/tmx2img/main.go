@@ -5,12 +5,24 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/disintegration/imaging"
 	"github.com/lafriks/go-tiled"
 	"github.com/lafriks/go-tiled/render"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "pyramid" {
+		pyramid(os.Args[2:])
+		return
+	}
+
+	animate := flag.Bool("animate", false, "render tile animations as animated GIF or PNG, depending on the image extension")
+	scale := flag.Float64("scale", 1, "scale factor of the image")
+	filter := flag.String("filter", "nearest", "resampling filter used to scale the image: nearest, linear, catmullrom or lanczos")
+	scaleTiles := flag.Bool("scaletiles", false, "scale images of tiles as they are drawn instead of the whole image")
 	flag.Parse()
 
 	filename := flag.Arg(0)
@@ -30,12 +42,12 @@ func main() {
 		fmt.Println(err)
 		return
 	}
-
-	if err = rend.RenderVisibleLayers(); err != nil {
+	rend.Scale = *scale
+	rend.ScaleTiles = *scaleTiles
+	if rend.ScaleFilter, err = resampleFilter(*filter); err != nil {
 		fmt.Println(err)
 		return
 	}
-	// rend.RenderLayer(1)
 
 	w, err := os.Create(img)
 	if err != nil {
@@ -43,8 +55,76 @@ func main() {
 		return
 	}
 	defer w.Close()
+
+	if *animate {
+		if strings.EqualFold(filepath.Ext(img), ".gif") {
+			err = rend.SaveAsAnimatedGif(w)
+		} else {
+			err = rend.SaveAsAnimatedPng(w)
+		}
+		if err != nil {
+			fmt.Println(err)
+		}
+		return
+	}
+
+	if err = rend.RenderVisibleMapLayers(); err != nil {
+		fmt.Println(err)
+		return
+	}
+	// rend.RenderLayer(1)
+
 	if err := rend.SaveAsPng(w); err != nil {
 		fmt.Println(err)
 		return
 	}
 }
+
+func resampleFilter(name string) (imaging.ResampleFilter, error) {
+	switch name {
+	case "nearest":
+		return imaging.NearestNeighbor, nil
+	case "linear":
+		return imaging.Linear, nil
+	case "catmullrom":
+		return imaging.CatmullRom, nil
+	case "lanczos":
+		return imaging.Lanczos, nil
+	}
+	return imaging.ResampleFilter{}, fmt.Errorf("unknown filter %q", name)
+}
+
+// pyramid writes the map as z/x/y.png tile pyramid for zoomable map viewers.
+func pyramid(args []string) {
+	flags := flag.NewFlagSet("pyramid", flag.ExitOnError)
+	tileSize := flags.Int("tilesize", render.DefaultPyramidTileSize, "size of the square tiles in pixels")
+	minZoom := flags.Int("minzoom", 0, "lowest zoom level that is written")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: tmx2img pyramid [options] map.tmx [output directory]")
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+
+	filename := flags.Arg(0)
+	dir := flags.Arg(1)
+	if dir == "" {
+		dir = "tiles"
+	}
+
+	m, err := tiled.LoadFile(filename)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	rend, err := render.NewRenderer(m)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	sink := &render.PNGTileSink{Files: render.DirFileCreator(dir)}
+	if err := rend.RenderPyramid(sink, &render.PyramidOptions{TileSize: *tileSize, MinZoom: *minZoom}); err != nil {
+		fmt.Println(err)
+	}
+}
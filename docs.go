@@ -1,2 +1,2 @@
-// Package tiled is to to parse Tiled map editor file format (TMX).
+// Package tiled is to to parse Tiled map editor file formats (TMX and JSON).
 package tiled
@@ -5,11 +5,12 @@ go 1.19
 require (
 	github.com/disintegration/imaging v1.6.2
 	github.com/stretchr/testify v1.8.2
+	golang.org/x/image v0.5.0
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	golang.org/x/image v0.5.0 // indirect
+	golang.org/x/text v0.7.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -58,3 +58,32 @@ func (s *sortable[T]) Less(i, j int) bool {
 func (s *sortable[T]) Len() int {
 	return len(s.data)
 }
+
+// Max returns the larger of a and b
+func Max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// Abs returns the absolute value of a
+func Abs(a int) int {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+// FloorDiv returns a divided by b rounded towards negative infinity, b must be positive
+func FloorDiv(a, b int) int {
+	if a < 0 {
+		return -((-a + b - 1) / b)
+	}
+	return a / b
+}
+
+// Mod returns the non-negative remainder of a divided by b, b must be positive
+func Mod(a, b int) int {
+	return (a%b + b) % b
+}
@@ -0,0 +1,173 @@
+/*
+Copyright (c) 2023 Lauris Bukšis-Haberkorns <lauris@nix.lv>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package render
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/disintegration/imaging"
+	tiled "github.com/lafriks/go-tiled"
+)
+
+// HexagonalRendererEngine represents hexagonal rendering engine. Tile positions depend on
+// the staggered axis, stagger index and the hex side length of the map.
+type HexagonalRendererEngine struct {
+	OrthogonalRendererEngine
+
+	tileWidth   int
+	tileHeight  int
+	sideLengthX int
+	sideLengthY int
+	sideOffsetX int
+	sideOffsetY int
+	columnWidth int
+	rowHeight   int
+	staggerX    bool
+	staggerEven bool
+}
+
+// Init initializes rendering engine with provided map options.
+func (e *HexagonalRendererEngine) Init(m *tiled.Map) {
+	e.init(m, m.HexSideLength)
+}
+
+func (e *HexagonalRendererEngine) init(m *tiled.Map, sideLength int) {
+	e.m = m
+	// Tile sizes are rounded down to even numbers, as in Tiled
+	e.tileWidth = m.TileWidth &^ 1
+	e.tileHeight = m.TileHeight &^ 1
+	e.staggerX = m.StaggerAxis == tiled.AxisX
+	e.staggerEven = m.StaggerIndex == tiled.StaggerIndexEven
+
+	e.sideLengthX, e.sideLengthY = 0, 0
+	if e.staggerX {
+		e.sideLengthX = sideLength
+	} else {
+		e.sideLengthY = sideLength
+	}
+
+	e.sideOffsetX = (e.tileWidth - e.sideLengthX) / 2
+	e.sideOffsetY = (e.tileHeight - e.sideLengthY) / 2
+	e.columnWidth = e.sideOffsetX + e.sideLengthX
+	e.rowHeight = e.sideOffsetY + e.sideLengthY
+}
+
+// RotateTileImage flips provided tile image and rotates it around its center. In hexagonal maps the diagonal
+// flip rotates tiles by 60 degrees clockwise, which is combined with the 120 degrees rotation.
+func (e *HexagonalRendererEngine) RotateTileImage(tile *tiled.LayerTile, img image.Image) image.Image {
+	timg := img
+	if tile.HorizontalFlip {
+		timg = imaging.FlipH(timg)
+	}
+	if tile.VerticalFlip {
+		timg = imaging.FlipV(timg)
+	}
+	if rotation := e.tileRotation(tile); rotation != 0 {
+		timg = imaging.Rotate(timg, -rotation, color.Transparent)
+	}
+
+	return timg
+}
+
+// tileRotation returns the clockwise rotation of the tile image around its center in degrees.
+func (e *HexagonalRendererEngine) tileRotation(tile *tiled.LayerTile) float64 {
+	rotation := 0.0
+	if tile.DiagonalFlip {
+		rotation += 60
+	}
+	if tile.RotatedHexagonal120 {
+		rotation += 120
+	}
+	return rotation
+}
+
+// GetFinalImageSize returns final image size based on map data.
+func (e *HexagonalRendererEngine) GetFinalImageSize() image.Rectangle {
+	if e.staggerX {
+		width := e.m.Width*e.columnWidth + e.sideOffsetX
+		height := e.m.Height * (e.tileHeight + e.sideLengthY)
+		if e.m.Width > 1 {
+			height += e.rowHeight
+		}
+		return image.Rect(0, 0, width, height)
+	}
+
+	width := e.m.Width * (e.tileWidth + e.sideLengthX)
+	height := e.m.Height*e.rowHeight + e.sideOffsetY
+	if e.m.Height > 1 {
+		width += e.columnWidth
+	}
+	return image.Rect(0, 0, width, height)
+}
+
+// GetTilePosition returns tile position in image.
+func (e *HexagonalRendererEngine) GetTilePosition(x, y int) image.Rectangle {
+	var left, top int
+	if e.staggerX {
+		left = x * e.columnWidth
+		top = y * (e.tileHeight + e.sideLengthY)
+		if e.IsStaggered(x) {
+			top += e.rowHeight
+		}
+	} else {
+		left = x * (e.tileWidth + e.sideLengthX)
+		top = y * e.rowHeight
+		if e.IsStaggered(y) {
+			left += e.columnWidth
+		}
+	}
+
+	return image.Rect(left, top, left+e.tileWidth, top+e.tileHeight)
+}
+
+// StaggerX returns true if columns are staggered and false if rows are staggered.
+func (e *HexagonalRendererEngine) StaggerX() bool {
+	return e.staggerX
+}
+
+// IsStaggered returns true if the column (for maps staggered along X axis) or the row
+// (for maps staggered along Y axis) with the given index is shifted.
+func (e *HexagonalRendererEngine) IsStaggered(index int) bool {
+	return (index&1 != 0) != e.staggerEven
+}
+
+// StaggeredRendererEngine represents staggered isometric rendering engine. It is the same as
+// hexagonal rendering with the hex side length of zero.
+type StaggeredRendererEngine struct {
+	HexagonalRendererEngine
+}
+
+// Init initializes rendering engine with provided map options.
+func (e *StaggeredRendererEngine) Init(m *tiled.Map) {
+	e.init(m, 0)
+}
+
+// RotateTileImage rotates provided tile layer. Staggered maps use the same flags as orthogonal maps.
+func (e *StaggeredRendererEngine) RotateTileImage(tile *tiled.LayerTile, img image.Image) image.Image {
+	return e.OrthogonalRendererEngine.RotateTileImage(tile, img)
+}
+
+func (e *StaggeredRendererEngine) tileRotation(tile *tiled.LayerTile) float64 {
+	return 0
+}
@@ -0,0 +1,198 @@
+/*
+Copyright (c) 2023 Lauris Bukšis-Haberkorns <lauris@nix.lv>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+
+	"github.com/disintegration/imaging"
+	"github.com/lafriks/go-tiled"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHexagonalRendererEngine(t *testing.T) {
+	tests := []struct {
+		name      string
+		engine    RendererEngine
+		m         *tiled.Map
+		size      image.Rectangle
+		positions map[image.Point]image.Point
+	}{
+		{
+			name:   "Hexagonal stagger Y odd",
+			engine: &HexagonalRendererEngine{},
+			m: &tiled.Map{Width: 3, Height: 3, TileWidth: 32, TileHeight: 28, HexSideLength: 14,
+				StaggerAxis: tiled.AxisY, StaggerIndex: tiled.StaggerIndexOdd},
+			size: image.Rect(0, 0, 112, 70),
+			positions: map[image.Point]image.Point{
+				{0, 0}: {0, 0},
+				{0, 1}: {16, 21},
+				{2, 2}: {64, 42},
+			},
+		},
+		{
+			name:   "Hexagonal stagger Y even",
+			engine: &HexagonalRendererEngine{},
+			m: &tiled.Map{Width: 3, Height: 3, TileWidth: 32, TileHeight: 28, HexSideLength: 14,
+				StaggerAxis: tiled.AxisY, StaggerIndex: tiled.StaggerIndexEven},
+			size: image.Rect(0, 0, 112, 70),
+			positions: map[image.Point]image.Point{
+				{0, 0}: {16, 0},
+				{0, 1}: {0, 21},
+				{2, 2}: {80, 42},
+			},
+		},
+		{
+			name:   "Hexagonal stagger X odd",
+			engine: &HexagonalRendererEngine{},
+			m: &tiled.Map{Width: 3, Height: 3, TileWidth: 28, TileHeight: 32, HexSideLength: 14,
+				StaggerAxis: tiled.AxisX, StaggerIndex: tiled.StaggerIndexOdd},
+			size: image.Rect(0, 0, 70, 112),
+			positions: map[image.Point]image.Point{
+				{0, 0}: {0, 0},
+				{1, 0}: {21, 16},
+				{2, 1}: {42, 32},
+			},
+		},
+		{
+			name:   "Hexagonal stagger X even",
+			engine: &HexagonalRendererEngine{},
+			m: &tiled.Map{Width: 3, Height: 3, TileWidth: 28, TileHeight: 32, HexSideLength: 14,
+				StaggerAxis: tiled.AxisX, StaggerIndex: tiled.StaggerIndexEven},
+			size: image.Rect(0, 0, 70, 112),
+			positions: map[image.Point]image.Point{
+				{0, 0}: {0, 16},
+				{1, 0}: {21, 0},
+				{2, 1}: {42, 48},
+			},
+		},
+		{
+			name:   "Staggered Y odd",
+			engine: &StaggeredRendererEngine{},
+			m: &tiled.Map{Width: 3, Height: 3, TileWidth: 64, TileHeight: 32, HexSideLength: 10,
+				StaggerAxis: tiled.AxisY, StaggerIndex: tiled.StaggerIndexOdd},
+			size: image.Rect(0, 0, 224, 64),
+			positions: map[image.Point]image.Point{
+				{0, 0}: {0, 0},
+				{0, 1}: {32, 16},
+				{1, 2}: {64, 32},
+			},
+		},
+		{
+			name:   "Staggered X even",
+			engine: &StaggeredRendererEngine{},
+			m: &tiled.Map{Width: 3, Height: 3, TileWidth: 64, TileHeight: 32,
+				StaggerAxis: tiled.AxisX, StaggerIndex: tiled.StaggerIndexEven},
+			size: image.Rect(0, 0, 128, 112),
+			positions: map[image.Point]image.Point{
+				{0, 0}: {0, 16},
+				{1, 0}: {32, 0},
+				{2, 2}: {64, 80},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.engine.Init(tt.m)
+			assert.Equal(t, tt.size, tt.engine.GetFinalImageSize())
+			for tile, pos := range tt.positions {
+				rect := image.Rectangle{Min: pos, Max: pos.Add(image.Pt(tt.m.TileWidth, tt.m.TileHeight))}
+				assert.Equal(t, rect, tt.engine.GetTilePosition(tile.X, tile.Y), "tile %v", tile)
+			}
+		})
+	}
+}
+
+func TestRenderHexagonal(t *testing.T) {
+	m, err := tiled.LoadFile("../assets/test_hexagonal.tmx")
+	assert.NoError(t, err)
+
+	r, err := NewRenderer(m)
+	assert.NoError(t, err)
+	assert.IsType(t, &HexagonalRendererEngine{}, r.engine)
+
+	// Shifted columns are drawn after the other columns of the same row
+	order, err := r.tileOrder()
+	assert.NoError(t, err)
+	assert.Equal(t, []image.Point{{1, 0}, {0, 0}, {2, 0}, {1, 1}, {0, 1}, {2, 1}}, order)
+
+	assert.NoError(t, r.RenderLayer(0))
+
+	expected := image.NewNRGBA(r.engine.GetFinalImageSize())
+	for _, p := range order {
+		tile := m.Layers[0].Tiles[p.Y*m.Width+p.X]
+		img, err := r.getTileImage(tile)
+		assert.NoError(t, err)
+		draw.Draw(expected, r.engine.GetTilePosition(p.X, p.Y), img, img.Bounds().Min, draw.Over)
+	}
+	assert.Equal(t, expected.Pix, r.Result.Pix)
+}
+
+func TestRenderHexagonalRotation(t *testing.T) {
+	m, err := tiled.LoadFile("../assets/test_hexagonal_rotation.tmx")
+	assert.NoError(t, err)
+
+	r, err := NewRenderer(m)
+	assert.NoError(t, err)
+	assert.NoError(t, r.RenderLayer(0))
+
+	order, err := r.tileOrder()
+	assert.NoError(t, err)
+
+	// The diagonal flip rotates tiles by 60 degrees and is combined with the 120 degrees rotation,
+	// rotated images are centered on the tile cell
+	rotations := map[image.Point]float64{{1, 0}: 120, {0, 1}: 60, {1, 1}: 180}
+	expected := image.NewNRGBA(r.engine.GetFinalImageSize())
+	for _, p := range order {
+		tile := m.Layers[0].Tiles[p.Y*m.Width+p.X]
+		if tile.IsNil() {
+			continue
+		}
+		plain := *tile
+		plain.DiagonalFlip, plain.RotatedHexagonal120 = false, false
+		img, err := r.getTileImage(&plain)
+		assert.NoError(t, err)
+		if p == image.Pt(1, 1) {
+			assert.Equal(t, imaging.FlipV(imaging.FlipH(img)), imaging.Rotate(img, -180, color.Transparent))
+		}
+		img = imaging.Rotate(img, -rotations[p], color.Transparent)
+
+		cell := r.engine.GetTilePosition(p.X, p.Y)
+		min := cell.Min.Add(cell.Size().Sub(img.Bounds().Size()).Div(2))
+		draw.Draw(expected, image.Rectangle{Min: min, Max: min.Add(img.Bounds().Size())}, img, img.Bounds().Min, draw.Over)
+	}
+	assert.Equal(t, expected.Pix, r.Result.Pix)
+
+	// Staggered maps do not use hexagonal rotations
+	m.Orientation = "staggered"
+	r, err = NewRenderer(m)
+	assert.NoError(t, err)
+	tile := m.Layers[0].Tiles[1]
+	img, err := r.getTileImage(tile)
+	assert.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 32, 32), img.Bounds())
+}
@@ -0,0 +1,69 @@
+/*
+Copyright (c) 2023 Lauris Bukšis-Haberkorns <lauris@nix.lv>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package render
+
+import (
+	"image"
+
+	tiled "github.com/lafriks/go-tiled"
+)
+
+// IsometricRendererEngine represents isometric rendering engine.
+type IsometricRendererEngine struct {
+	OrthogonalRendererEngine
+}
+
+// Init initializes rendering engine with provided map options.
+func (e *IsometricRendererEngine) Init(m *tiled.Map) {
+	e.m = m
+}
+
+// GetFinalImageSize returns final image size based on map data.
+func (e *IsometricRendererEngine) GetFinalImageSize() image.Rectangle {
+	side := e.m.Width + e.m.Height
+	return image.Rect(0, 0, side*e.m.TileWidth/2, side*e.m.TileHeight/2)
+}
+
+// GetTilePosition returns tile position in image. The position is the bounding box
+// of the tile diamond.
+func (e *IsometricRendererEngine) GetTilePosition(x, y int) image.Rectangle {
+	originX := e.m.Height * e.m.TileWidth / 2
+	left := (x-y)*e.m.TileWidth/2 + originX - e.m.TileWidth/2
+	top := (x + y) * e.m.TileHeight / 2
+
+	return image.Rect(left, top, left+e.m.TileWidth, top+e.m.TileHeight)
+}
+
+// PixelToScreenCoords converts object pixel coordinates to coordinates in image.
+// In isometric maps object coordinates are measured along tile axes, where tile
+// height is used as the length of a tile on both axes.
+func (e *IsometricRendererEngine) PixelToScreenCoords(x, y float64) (float64, float64) {
+	tileWidth := float64(e.m.TileWidth)
+	tileHeight := float64(e.m.TileHeight)
+	originX := float64(e.m.Height) * tileWidth / 2
+
+	tileX := x / tileHeight
+	tileY := y / tileHeight
+
+	return (tileX-tileY)*tileWidth/2 + originX, (tileX + tileY) * tileHeight / 2
+}
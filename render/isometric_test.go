@@ -0,0 +1,109 @@
+/*
+Copyright (c) 2023 Lauris Bukšis-Haberkorns <lauris@nix.lv>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package render
+
+import (
+	"image"
+	"image/draw"
+	"testing"
+
+	"github.com/lafriks/go-tiled"
+	"github.com/stretchr/testify/assert"
+)
+
+// assertDrawn checks that img was drawn to the rectangle of the renderer result
+func assertDrawn(t *testing.T, r *Renderer, img image.Image, rect image.Rectangle) {
+	t.Helper()
+
+	expected := image.NewNRGBA(r.Result.Bounds())
+	draw.Draw(expected, rect, img, img.Bounds().Min, draw.Over)
+	assert.Equal(t, expected.Pix, r.Result.Pix)
+}
+
+func TestIsometricRendererEngine(t *testing.T) {
+	m, err := tiled.LoadFile("../assets/test_isometric.tmx")
+	assert.NoError(t, err)
+
+	e := &IsometricRendererEngine{}
+	e.Init(m)
+
+	assert.Equal(t, image.Rect(0, 0, 64, 32), e.GetFinalImageSize())
+	assert.Equal(t, image.Rect(16, 0, 48, 16), e.GetTilePosition(0, 0))
+	assert.Equal(t, image.Rect(32, 8, 64, 24), e.GetTilePosition(1, 0))
+	assert.Equal(t, image.Rect(0, 8, 32, 24), e.GetTilePosition(0, 1))
+	assert.Equal(t, image.Rect(16, 16, 48, 32), e.GetTilePosition(1, 1))
+
+	x, y := e.PixelToScreenCoords(0, 0)
+	assert.Equal(t, []float64{32, 0}, []float64{x, y})
+	x, y = e.PixelToScreenCoords(32, 0)
+	assert.Equal(t, []float64{64, 16}, []float64{x, y})
+	x, y = e.PixelToScreenCoords(16, 16)
+	assert.Equal(t, []float64{32, 16}, []float64{x, y})
+}
+
+// basicEngine only has methods of RendererEngine, like engines implemented outside of the package
+type basicEngine struct {
+	RendererEngine
+}
+
+func TestRenderEngineWithoutScreenCoords(t *testing.T) {
+	m, err := tiled.LoadFile("../assets/test_render_shapes.tmx")
+	assert.NoError(t, err)
+
+	expected, err := NewRenderer(m)
+	assert.NoError(t, err)
+	assert.NoError(t, expected.RenderVisibleMapLayers())
+
+	// Objects are placed at their pixel coordinates, as in orthogonal maps
+	r, err := NewRenderer(m)
+	assert.NoError(t, err)
+	r.engine = basicEngine{r.engine}
+	assert.NoError(t, r.RenderVisibleMapLayers())
+	assert.Equal(t, expected.Result.Pix, r.Result.Pix)
+}
+
+func TestRenderIsometric(t *testing.T) {
+	m, err := tiled.LoadFile("../assets/test_isometric.tmx")
+	assert.NoError(t, err)
+
+	r, err := NewRenderer(m)
+	assert.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 64, 32), r.ResultImage().Bounds())
+
+	// Tall tile is aligned to the bottom of the tile diamond bounding box
+	assert.NoError(t, r.RenderLayer(0))
+	tile, err := m.TileGIDToTile(21)
+	assert.NoError(t, err)
+	img, err := r.getTileImage(tile)
+	assert.NoError(t, err)
+	assertDrawn(t, r, img, image.Rect(32, -8, 64, 24))
+
+	// Tile object is aligned to its bottom-center
+	r.Clear()
+	assert.NoError(t, r.RenderObjectGroup(0))
+	tile, err = m.TileGIDToTile(1)
+	assert.NoError(t, err)
+	img, err = r.getTileImage(tile)
+	assert.NoError(t, err)
+	assertDrawn(t, r, img, image.Rect(16, -16, 48, 16))
+}
@@ -67,3 +67,8 @@ func (e *OrthogonalRendererEngine) GetTilePosition(x, y int) image.Rectangle {
 		(x+1)*e.m.TileWidth,
 		(y+1)*e.m.TileHeight)
 }
+
+// PixelToScreenCoords converts object pixel coordinates to coordinates in image.
+func (e *OrthogonalRendererEngine) PixelToScreenCoords(x, y float64) (float64, float64) {
+	return x, y
+}
@@ -0,0 +1,387 @@
+/*
+Copyright (c) 2023 Lauris Bukšis-Haberkorns <lauris@nix.lv>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package render
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lafriks/go-tiled"
+)
+
+// MaxAnimationLoop limits the duration of the combined animation loop of the map, which is the least
+// common multiple of durations of all tile animations.
+var MaxAnimationLoop = 10 * time.Minute
+
+// Frame is a single frame of the rendered map animation.
+type Frame struct {
+	// Rendered image of the frame
+	Image *image.NRGBA
+	// How long the frame is displayed before advancing to the next frame
+	Duration time.Duration
+}
+
+// animationCache caches animation frames of tiles by their global tile ID, nil for tiles that are not animated.
+type animationCache struct {
+	mu     sync.Mutex
+	frames map[uint32][]*tiled.AnimationFrame
+}
+
+// SetTime sets the time used to select frames of animated tiles. Tile animations are repeated,
+// so any time can be used.
+func (r *Renderer) SetTime(t time.Duration) {
+	r.animationTime = t
+}
+
+// animatedTileID returns ID of the tile drawn for the layer tile at the current time.
+func (r *Renderer) animatedTileID(tile *tiled.LayerTile) uint32 {
+	frames := r.tileAnimation(tile.Tileset, tile.ID)
+	total := animationDuration(frames)
+	if total == 0 {
+		return tile.ID
+	}
+
+	t := r.animationTime % total
+	if t < 0 {
+		t += total
+	}
+	for _, f := range frames {
+		d := time.Duration(f.Duration) * time.Millisecond
+		if t < d {
+			return f.TileID
+		}
+		t -= d
+	}
+	return tile.ID
+}
+
+// tileAnimation returns animation frames of the tile, or nil if the tile is not animated.
+func (r *Renderer) tileAnimation(ts *tiled.Tileset, id uint32) []*tiled.AnimationFrame {
+	r.animations.mu.Lock()
+	defer r.animations.mu.Unlock()
+
+	frames, ok := r.animations.frames[ts.FirstGID+id]
+	if !ok {
+		if t, err := ts.GetTilesetTile(id); err == nil {
+			frames = t.Animation
+		}
+		r.animations.frames[ts.FirstGID+id] = frames
+	}
+	return frames
+}
+
+func animationDuration(frames []*tiled.AnimationFrame) time.Duration {
+	var total time.Duration
+	for _, f := range frames {
+		total += time.Duration(f.Duration) * time.Millisecond
+	}
+	return total
+}
+
+// visibleAnimations returns animations of all distinct animated tiles used by visible tile layers and tile objects.
+func (r *Renderer) visibleAnimations() [][]*tiled.AnimationFrame {
+	var animations [][]*tiled.AnimationFrame
+	seen := make(map[uint32]bool)
+	add := func(tile *tiled.LayerTile) {
+		if tile == nil || tile.IsNil() || tile.Tileset == nil || seen[tile.Tileset.FirstGID+tile.ID] {
+			return
+		}
+		seen[tile.Tileset.FirstGID+tile.ID] = true
+		if frames := r.tileAnimation(tile.Tileset, tile.ID); animationDuration(frames) > 0 {
+			animations = append(animations, frames)
+		}
+	}
+
+	_ = r.m.WalkLayers(func(layer tiled.MapLayer, state *tiled.LayerState) error {
+		if !state.Visible {
+			return tiled.SkipGroup
+		}
+		switch l := layer.(type) {
+		case *tiled.Layer:
+			for _, tile := range l.Tiles {
+				add(tile)
+			}
+		case *tiled.ObjectGroup:
+			for _, o := range l.Objects {
+				if o.GID == 0 || !o.Visible {
+					continue
+				}
+				if tile, err := r.m.TileGIDToTile(o.GID); err == nil {
+					add(tile)
+				}
+			}
+		}
+		return nil
+	})
+	return animations
+}
+
+// AnimationTimeline returns times at which the image of any animated tile of the visible layers changes,
+// starting with zero, and the duration of the animation loop after which all animations repeat. For
+// maps without animated tiles the loop duration is zero. The loop is limited to MaxAnimationLoop.
+func (r *Renderer) AnimationTimeline() ([]time.Duration, time.Duration) {
+	animations := r.visibleAnimations()
+
+	var loop time.Duration
+	for _, frames := range animations {
+		total := animationDuration(frames)
+		if loop == 0 {
+			loop = total
+		} else {
+			loop = loop / gcd(loop, total) * total
+		}
+		if loop > MaxAnimationLoop {
+			loop = MaxAnimationLoop
+			break
+		}
+	}
+
+	changes := map[time.Duration]bool{0: true}
+	for _, frames := range animations {
+		for t := time.Duration(0); t < loop; {
+			for _, f := range frames {
+				if t >= loop {
+					break
+				}
+				changes[t] = true
+				t += time.Duration(f.Duration) * time.Millisecond
+			}
+		}
+	}
+
+	times := make([]time.Duration, 0, len(changes))
+	for t := range changes {
+		times = append(times, t)
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+	return times, loop
+}
+
+func gcd(a, b time.Duration) time.Duration {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// RenderAnimation renders all visible layers of the map for each frame of the animation timeline.
+// Maps without animated tiles are rendered as a single frame with zero duration. The render result
+// is left with the image of the last frame. All frames are kept in memory as images of the whole
+// map, use SaveAsAnimatedGif or SaveAsAnimatedPng to encode frames as they are rendered instead.
+func (r *Renderer) RenderAnimation() ([]Frame, error) {
+	times, loop := r.AnimationTimeline()
+	frames := make([]Frame, 0, len(times))
+	err := r.renderAnimationFrames(times, loop, func(f Frame) error {
+		frames = append(frames, f)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return frames, nil
+}
+
+// renderAnimationFrames renders frames at the times of the animation timeline and calls fn for each of them.
+func (r *Renderer) renderAnimationFrames(times []time.Duration, loop time.Duration, fn func(f Frame) error) error {
+	for i, t := range times {
+		r.SetTime(t)
+		r.Clear()
+		if err := r.RenderVisibleMapLayers(); err != nil {
+			return err
+		}
+		// Result image is not allocated when there are no visible layers
+		r.ensureResult()
+
+		end := loop
+		if i+1 < len(times) {
+			end = times[i+1]
+		}
+		if err := fn(Frame{Image: r.Result, Duration: end - t}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SaveAsAnimatedGif renders the map animation and writes it as looping animated GIF image to provided writer.
+// Frames are converted to paletted images as they are rendered.
+func (r *Renderer) SaveAsAnimatedGif(w io.Writer) error {
+	// First color of the palette is used for transparent pixels
+	pal := append(color.Palette{color.Transparent}, palette.Plan9[:255]...)
+
+	anim := &gif.GIF{}
+	times, loop := r.AnimationTimeline()
+	err := r.renderAnimationFrames(times, loop, func(f Frame) error {
+		bounds := f.Image.Bounds()
+		img := image.NewPaletted(bounds, pal)
+		draw.FloydSteinberg.Draw(img, bounds, f.Image, bounds.Min)
+
+		anim.Image = append(anim.Image, img)
+		anim.Delay = append(anim.Delay, int((f.Duration+5*time.Millisecond)/(10*time.Millisecond)))
+		anim.Disposal = append(anim.Disposal, gif.DisposalBackground)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return gif.EncodeAll(w, anim)
+}
+
+// SaveAsAnimatedPng renders the map animation and writes it as looping animated PNG (APNG) image to provided writer.
+// Frames are compressed and written as they are rendered.
+func (r *Renderer) SaveAsAnimatedPng(w io.Writer) error {
+	times, loop := r.AnimationTimeline()
+	enc := &apngEncoder{pw: &pngWriter{w: w}, numFrames: len(times)}
+	if err := r.renderAnimationFrames(times, loop, enc.writeFrame); err != nil {
+		return err
+	}
+	return enc.close()
+}
+
+// pngWriter writes PNG chunks and keeps the first error.
+type pngWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (pw *pngWriter) writeChunk(name string, data []byte) {
+	if pw.err != nil {
+		return
+	}
+
+	header := make([]byte, 8)
+	binary.BigEndian.PutUint32(header, uint32(len(data)))
+	copy(header[4:], name)
+
+	crc := crc32.NewIEEE()
+	_, _ = crc.Write(header[4:])
+	_, _ = crc.Write(data)
+
+	for _, b := range [][]byte{header, data, crc.Sum(nil)} {
+		if _, pw.err = pw.w.Write(b); pw.err != nil {
+			return
+		}
+	}
+}
+
+// apngEncoder writes frames as animated PNG. All frames are stored as 8-bit RGBA images of the first frame size.
+type apngEncoder struct {
+	pw        *pngWriter
+	numFrames int
+	bounds    image.Rectangle
+	frame     int
+	seq       uint32
+}
+
+// writeFrame writes the frame, preceded by the PNG header for the first frame.
+func (e *apngEncoder) writeFrame(f Frame) error {
+	pw := e.pw
+	if e.frame == 0 {
+		e.bounds = f.Image.Bounds()
+		if _, pw.err = io.WriteString(pw.w, "\x89PNG\r\n\x1a\n"); pw.err != nil {
+			return pw.err
+		}
+
+		ihdr := make([]byte, 13)
+		binary.BigEndian.PutUint32(ihdr[0:], uint32(e.bounds.Dx()))
+		binary.BigEndian.PutUint32(ihdr[4:], uint32(e.bounds.Dy()))
+		ihdr[8] = 8 // Bit depth
+		ihdr[9] = 6 // Truecolor with alpha
+		pw.writeChunk("IHDR", ihdr)
+
+		actl := make([]byte, 8)
+		binary.BigEndian.PutUint32(actl[0:], uint32(e.numFrames))
+		// Zero number of plays means the animation loops forever
+		pw.writeChunk("acTL", actl)
+	}
+
+	delayNum, delayDen := f.Duration.Milliseconds(), int64(1000)
+	for delayNum > 0xffff && delayDen > 1 {
+		delayNum, delayDen = delayNum/10, delayDen/10
+	}
+	if delayNum > 0xffff {
+		delayNum = 0xffff
+	}
+
+	fctl := make([]byte, 26)
+	binary.BigEndian.PutUint32(fctl[0:], e.seq)
+	binary.BigEndian.PutUint32(fctl[4:], uint32(e.bounds.Dx()))
+	binary.BigEndian.PutUint32(fctl[8:], uint32(e.bounds.Dy()))
+	binary.BigEndian.PutUint16(fctl[20:], uint16(delayNum))
+	binary.BigEndian.PutUint16(fctl[22:], uint16(delayDen))
+	// Frame area is not disposed and replaces the previous frame, as every frame covers the whole image
+	pw.writeChunk("fcTL", fctl)
+	e.seq++
+
+	data, err := compressAPNGFrame(f.Image, e.bounds)
+	if err != nil {
+		return err
+	}
+	if e.frame == 0 {
+		pw.writeChunk("IDAT", data)
+	} else {
+		fdat := make([]byte, 4, 4+len(data))
+		binary.BigEndian.PutUint32(fdat, e.seq)
+		pw.writeChunk("fdAT", append(fdat, data...))
+		e.seq++
+	}
+	e.frame++
+	return pw.err
+}
+
+// close writes the end of the PNG image.
+func (e *apngEncoder) close() error {
+	e.pw.writeChunk("IEND", nil)
+	return e.pw.err
+}
+
+// compressAPNGFrame returns zlib compressed RGBA scanlines of the image, each preceded by the filter type byte.
+func compressAPNGFrame(img *image.NRGBA, bounds image.Rectangle) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		start := img.PixOffset(bounds.Min.X, y)
+		// Filter type none
+		if _, err := zw.Write([]byte{0}); err != nil {
+			return nil, err
+		}
+		if _, err := zw.Write(img.Pix[start : start+4*bounds.Dx()]); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,188 @@
+/*
+Copyright (c) 2023 Lauris Bukšis-Haberkorns <lauris@nix.lv>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package render
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/lafriks/go-tiled"
+	"github.com/stretchr/testify/assert"
+)
+
+const ms = time.Millisecond
+
+// expectedAnimationFrame returns the map image with the tiles of given IDs.
+func expectedAnimationFrame(t *testing.T, r *Renderer, ids ...uint32) *image.NRGBA {
+	t.Helper()
+
+	img := image.NewNRGBA(r.Result.Bounds())
+	for x, id := range ids {
+		tile, err := r.m.TileGIDToTile(id + 1)
+		assert.NoError(t, err)
+		timg, err := r.getTileImage(tile)
+		assert.NoError(t, err)
+		draw.Draw(img, image.Rect(x*32, 0, (x+1)*32, 32), timg, timg.Bounds().Min, draw.Over)
+	}
+	return img
+}
+
+func TestAnimationTimeline(t *testing.T) {
+	m, err := tiled.LoadFile("../assets/test_render_animation.tmx")
+	assert.NoError(t, err)
+
+	r, err := NewRenderer(m)
+	assert.NoError(t, err)
+
+	times, loop := r.AnimationTimeline()
+	assert.Equal(t, 1500*ms, loop)
+	assert.Equal(t, []time.Duration{
+		0, 150 * ms, 200 * ms, 300 * ms, 450 * ms, 500 * ms, 600 * ms, 700 * ms, 750 * ms,
+		900 * ms, 1000 * ms, 1050 * ms, 1200 * ms, 1350 * ms,
+	}, times)
+
+	// Hidden layers are not animated
+	m.Layers[0].Visible = false
+	times, loop = r.AnimationTimeline()
+	assert.Equal(t, time.Duration(0), loop)
+	assert.Equal(t, []time.Duration{0}, times)
+}
+
+func TestRenderAtTime(t *testing.T) {
+	m, err := tiled.LoadFile("../assets/test_render_animation.tmx")
+	assert.NoError(t, err)
+
+	r, err := NewRenderer(m)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		time time.Duration
+		ids  []uint32
+	}{
+		{0, []uint32{1, 4}},
+		{199 * ms, []uint32{1, 5}},
+		{250 * ms, []uint32{2, 5}},
+		{500 * ms, []uint32{1, 5}},
+		{1600 * ms, []uint32{1, 4}},
+	}
+	for _, tt := range tests {
+		r.SetTime(tt.time)
+		r.Clear()
+		assert.NoError(t, r.RenderLayer(0))
+		assert.Equal(t, expectedAnimationFrame(t, r, tt.ids...).Pix, r.Result.Pix, "time %v", tt.time)
+	}
+}
+
+func TestRenderAnimation(t *testing.T) {
+	m, err := tiled.LoadFile("../assets/test_render_animation.tmx")
+	assert.NoError(t, err)
+
+	r, err := NewRenderer(m)
+	assert.NoError(t, err)
+
+	frames, err := r.RenderAnimation()
+	assert.NoError(t, err)
+	assert.Len(t, frames, 14)
+	var total time.Duration
+	for _, f := range frames {
+		total += f.Duration
+	}
+	assert.Equal(t, 1500*ms, total)
+	assert.Equal(t, 150*ms, frames[0].Duration)
+	assert.Equal(t, expectedAnimationFrame(t, r, 1, 4).Pix, frames[0].Image.Pix)
+	assert.Equal(t, expectedAnimationFrame(t, r, 1, 5).Pix, frames[1].Image.Pix)
+	assert.Equal(t, expectedAnimationFrame(t, r, 2, 5).Pix, frames[2].Image.Pix)
+
+	var buf bytes.Buffer
+	assert.NoError(t, r.SaveAsAnimatedGif(&buf))
+	anim, err := gif.DecodeAll(&buf)
+	assert.NoError(t, err)
+	assert.Len(t, anim.Image, 14)
+	assert.Equal(t, 15, anim.Delay[0])
+	assert.Equal(t, 5, anim.Delay[1])
+	assert.Equal(t, 0, anim.LoopCount)
+
+	buf.Reset()
+	assert.NoError(t, r.SaveAsAnimatedPng(&buf))
+	data := buf.Bytes()
+
+	// Default image of the APNG is the first frame
+	img, err := png.Decode(bytes.NewReader(data))
+	assert.NoError(t, err)
+	first := image.NewNRGBA(img.Bounds())
+	draw.Draw(first, first.Bounds(), img, image.Point{}, draw.Src)
+	assert.Equal(t, frames[0].Image.Pix, first.Pix)
+
+	// Count animation chunks
+	chunks := map[string]int{}
+	var numFrames uint32
+	for pos := 8; pos+8 <= len(data); {
+		length := int(binary.BigEndian.Uint32(data[pos:]))
+		name := string(data[pos+4 : pos+8])
+		if name == "acTL" {
+			numFrames = binary.BigEndian.Uint32(data[pos+8:])
+		}
+		chunks[name]++
+		pos += 12 + length
+	}
+	assert.Equal(t, uint32(14), numFrames)
+	assert.Equal(t, 14, chunks["fcTL"])
+	assert.Equal(t, 1, chunks["IDAT"])
+	assert.Equal(t, 13, chunks["fdAT"])
+	assert.Equal(t, 1, chunks["IEND"])
+}
+
+func TestRenderAnimationHiddenLayers(t *testing.T) {
+	m, err := tiled.LoadFile("../assets/test_render_animation.tmx")
+	assert.NoError(t, err)
+	for _, l := range m.Layers {
+		l.Visible = false
+	}
+
+	r, err := NewRenderer(m)
+	assert.NoError(t, err)
+
+	frames, err := r.RenderAnimation()
+	assert.NoError(t, err)
+	assert.Len(t, frames, 1)
+	assert.NotNil(t, frames[0].Image)
+	assert.True(t, frames[0].Image.Bounds().Eq(r.imageBounds()))
+
+	var buf bytes.Buffer
+	assert.NoError(t, r.SaveAsAnimatedGif(&buf))
+	anim, err := gif.DecodeAll(&buf)
+	assert.NoError(t, err)
+	assert.Len(t, anim.Image, 1)
+
+	buf.Reset()
+	assert.NoError(t, r.SaveAsAnimatedPng(&buf))
+	img, err := png.Decode(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, frames[0].Image.Bounds(), img.Bounds())
+}
@@ -0,0 +1,147 @@
+/*
+Copyright (c) 2023 Lauris Bukšis-Haberkorns <lauris@nix.lv>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	"github.com/lafriks/go-tiled"
+	"github.com/lafriks/go-tiled/internal/utils"
+)
+
+// RenderVisibleImageLayers renders all visible top level image layers.
+func (r *Renderer) RenderVisibleImageLayers() error {
+	for i, layer := range r.m.ImageLayers {
+		if !layer.Visible {
+			continue
+		}
+		if err := r.RenderImageLayer(i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RenderImageLayer renders a single image layer.
+func (r *Renderer) RenderImageLayer(i int) error {
+	if i >= len(r.m.ImageLayers) {
+		return ErrOutOfBounds
+	}
+	layer := r.m.ImageLayers[i]
+	return r._renderImageLayer(layer, r.layerState(layer))
+}
+
+func (r *Renderer) _renderImageLayer(layer *tiled.ImageLayer, state *tiled.LayerState) error {
+	return r.composite(func() error {
+		return r.drawImageLayer(layer, state)
+	})
+}
+
+func (r *Renderer) drawImageLayer(layer *tiled.ImageLayer, state *tiled.LayerState) error {
+	if layer.Image == nil || (len(layer.Image.Source) == 0 && !layer.Image.IsEmbedded()) {
+		return nil
+	}
+
+	fileName := r.m.GetFileFullPath(layer.Image.Source)
+	img, err := r.TileCache.layerImage(fileName, layer.Image, func() (image.Image, error) {
+		return r.loadImage(layer.Image, fileName)
+	})
+	if err != nil {
+		return err
+	}
+
+	size := img.Bounds().Size()
+	if size.X == 0 || size.Y == 0 {
+		return nil
+	}
+
+	// The deprecated layer position is added to the offset, same as Tiled does when loading such maps
+	pos := image.Pt(layer.X, layer.Y).Add(r.layerOrigin(state))
+
+	// Repeated images fill the whole result along the axis, starting at the layer position
+	bounds := r.resultBounds()
+	startX, endX := pos.X, pos.X+size.X
+	if layer.RepeatX {
+		startX = bounds.Min.X - utils.Mod(bounds.Min.X-pos.X, size.X)
+		endX = bounds.Max.X
+	}
+	startY, endY := pos.Y, pos.Y+size.Y
+	if layer.RepeatY {
+		startY = bounds.Min.Y - utils.Mod(bounds.Min.Y-pos.Y, size.Y)
+		endY = bounds.Max.Y
+	}
+
+	for y := startY; y < endY; y += size.Y {
+		for x := startX; x < endX; x += size.X {
+			rect := image.Rectangle{Min: image.Pt(x, y), Max: image.Pt(x, y).Add(size)}
+			r.drawImage(rect, img, state)
+		}
+	}
+
+	return nil
+}
+
+// loadImage decodes the image and makes pixels of its transparent color, if provided, transparent.
+func (r *Renderer) loadImage(img *tiled.Image, fileName string) (image.Image, error) {
+	decoded, err := r.decodeImage(img, fileName)
+	if err != nil {
+		return nil, err
+	}
+	if img.Trans == nil {
+		return decoded, nil
+	}
+	return keyTransparentColor(decoded, img.Trans), nil
+}
+
+// decodeImage decodes the image embedded in the map or tileset, or the image file otherwise.
+func (r *Renderer) decodeImage(img *tiled.Image, fileName string) (image.Image, error) {
+	if img.IsEmbedded() {
+		return img.DecodeData()
+	}
+
+	f, err := r.open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	decoded, _, err := image.Decode(f)
+	return decoded, err
+}
+
+// keyTransparentColor returns a copy of the image where all pixels of the given color are transparent.
+func keyTransparentColor(img image.Image, trans *tiled.HexColor) image.Image {
+	key := color.NRGBAModel.Convert(trans).(color.NRGBA)
+
+	bounds := img.Bounds()
+	dst := image.NewNRGBA(bounds)
+	draw.Draw(dst, bounds, img, bounds.Min, draw.Src)
+	for i := 0; i < len(dst.Pix); i += 4 {
+		if dst.Pix[i] == key.R && dst.Pix[i+1] == key.G && dst.Pix[i+2] == key.B {
+			dst.Pix[i+3] = 0
+		}
+	}
+	return dst
+}
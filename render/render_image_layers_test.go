@@ -0,0 +1,141 @@
+/*
+Copyright (c) 2023 Lauris Bukšis-Haberkorns <lauris@nix.lv>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+
+	"github.com/lafriks/go-tiled"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderImageLayers(t *testing.T) {
+	m, err := tiled.LoadFile("../assets/test_image_layer.tmx")
+	assert.NoError(t, err)
+
+	r, err := NewRenderer(m)
+	assert.NoError(t, err)
+	assert.NoError(t, r.RenderVisibleMapLayers())
+
+	magenta := color.NRGBA{255, 0, 255, 255}
+	red := color.NRGBA{255, 0, 0, 255}
+	transparent := color.NRGBA{}
+	half := func(c color.NRGBA) color.NRGBA {
+		c.A = 127
+		return c
+	}
+
+	// Keyed image is drawn at the layer offset without the transparent color
+	assert.Equal(t, transparent, r.Result.NRGBAAt(4, 2))
+	assert.Equal(t, red, r.Result.NRGBAAt(6, 4))
+	assert.Equal(t, red, r.Result.NRGBAAt(9, 7))
+	assert.Equal(t, transparent, r.Result.NRGBAAt(10, 8))
+
+	// Repeated image fills the whole width, with the layer opacity applied
+	for x := 0; x < 32; x++ {
+		expected := magenta
+		if x%8 >= 2 && x%8 < 6 {
+			expected = red
+		}
+		assert.Equal(t, half(magenta), r.Result.NRGBAAt(x, 24))
+		assert.Equal(t, half(expected), r.Result.NRGBAAt(x, 28))
+	}
+	assert.Equal(t, transparent, r.Result.NRGBAAt(0, 23))
+
+	expected, err := NewRenderer(m)
+	assert.NoError(t, err)
+	assert.NoError(t, expected.RenderVisibleImageLayers())
+	assert.Equal(t, expected.Result, r.Result)
+
+	assert.ErrorIs(t, r.RenderImageLayer(2), ErrOutOfBounds)
+}
+
+func TestRenderRepeatedImageLayer(t *testing.T) {
+	m, err := tiled.LoadFile("../assets/test_image_layer.tmx")
+	assert.NoError(t, err)
+
+	// Repeating starts at the layer position and continues in both directions
+	layer := m.ImageLayers[0]
+	layer.RepeatX, layer.RepeatY = true, true
+	r, err := NewRenderer(m)
+	assert.NoError(t, err)
+	assert.NoError(t, r.RenderImageLayer(0))
+
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 32; x++ {
+			expected := color.NRGBA{}
+			if p := image.Pt(x-4, y-2).Mod(image.Rect(0, 0, 8, 8)); p.X >= 2 && p.X < 6 && p.Y >= 2 && p.Y < 6 {
+				expected = color.NRGBA{255, 0, 0, 255}
+			}
+			if !assert.Equal(t, expected, r.Result.NRGBAAt(x, y), "pixel %d,%d", x, y) {
+				return
+			}
+		}
+	}
+}
+
+func TestRenderImageLayersCached(t *testing.T) {
+	m, err := tiled.LoadFile("../assets/test_image_layer.tmx")
+	assert.NoError(t, err)
+
+	files := &countingFS{counts: make(map[string]int)}
+	r, err := NewRendererWithFileSystem(m, files)
+	assert.NoError(t, err)
+	assert.NoError(t, r.RenderVisibleMapLayers())
+	expected := r.Result
+
+	// Image is decoded once with and once without the transparent color, and reused by later renders
+	r.Clear()
+	assert.NoError(t, r.RenderVisibleMapLayers())
+	assert.Equal(t, expected.Pix, r.Result.Pix)
+	dst := image.NewNRGBA(image.Rect(0, 0, 16, 16))
+	assert.NoError(t, r.RenderRegion(dst, image.Rect(8, 16, 24, 32)))
+	assert.Equal(t, map[string]int{m.GetFileFullPath("test_image_layer.png"): 2}, files.counts)
+}
+
+func TestRenderEmbeddedImages(t *testing.T) {
+	m, err := tiled.LoadFile("../assets/test_image_embedded.tmx")
+	assert.NoError(t, err)
+
+	r, err := NewRenderer(m)
+	assert.NoError(t, err)
+
+	// Tileset image data is split into tiles like an image file
+	assert.NoError(t, r.RenderLayer(0))
+	img, err := r.loadImage(&tiled.Image{}, "../assets/test_image_layer.png")
+	assert.NoError(t, err)
+	expected := image.NewNRGBA(r.Result.Bounds())
+	draw.Draw(expected, image.Rect(4, 4, 12, 12), img, image.Point{}, draw.Over)
+	assert.Equal(t, expected.Pix, r.Result.Pix)
+
+	// Image layer data is keyed with the transparent color
+	r.Clear()
+	assert.NoError(t, r.RenderImageLayer(0))
+	assert.Equal(t, color.NRGBA{}, r.Result.NRGBAAt(4, 2))
+	assert.Equal(t, color.NRGBA{255, 0, 0, 255}, r.Result.NRGBAAt(6, 4))
+	assert.Equal(t, color.NRGBA{255, 0, 0, 255}, r.Result.NRGBAAt(9, 7))
+	assert.Equal(t, color.NRGBA{}, r.Result.NRGBAAt(10, 8))
+}
@@ -25,7 +25,6 @@ package render
 import (
 	"image"
 	"image/color"
-	"image/draw"
 	"math"
 
 	"github.com/lafriks/go-tiled"
@@ -58,37 +57,23 @@ func (r *Renderer) RenderGroup(groupID int) error {
 }
 
 func (r *Renderer) _renderGroup(group *tiled.Group) error {
-	for _, layer := range group.Layers {
-		if !layer.Visible {
-			continue
-		}
-		if err := r._renderLayer(layer); err != nil {
-			return err
-		}
-	}
-
-	for _, objectGroup := range group.ObjectGroups {
-		if !objectGroup.Visible {
-			continue
-		}
-		if err := r._renderObjectGroup(objectGroup); err != nil {
-			return err
-		}
-	}
-
-	return nil
+	return r.RenderMapLayer(group)
 }
 
-// RenderVisibleLayersAndObjectGroups render all layers and object groups, layer first, objectGroup second
-// so the order may be incorrect,
-// you may put them into different groups, then call RenderVisibleGroups
+// RenderVisibleLayersAndObjectGroups renders all visible top level tile layers and object groups
+// in the order they appear in the map. Use RenderVisibleMapLayers to render layers of all kinds.
 func (r *Renderer) RenderVisibleLayersAndObjectGroups() error {
-	// TODO: The order maybe incorrect
-
-	if err := r.RenderVisibleLayers(); err != nil {
-		return err
-	}
-	return r.RenderVisibleObjectGroups()
+	return r.m.WalkLayers(func(layer tiled.MapLayer, state *tiled.LayerState) error {
+		if !state.Visible {
+			return tiled.SkipGroup
+		}
+		switch layer.Kind() {
+		case tiled.LayerKindTile, tiled.LayerKindObjectGroup:
+			return r.renderLayerContent(layer, state)
+		}
+		// Layers nested in groups are not rendered
+		return tiled.SkipGroup
+	})
 }
 
 // RenderVisibleObjectGroups renders all visible object groups
@@ -111,11 +96,18 @@ func (r *Renderer) RenderObjectGroup(i int) error {
 	}
 
 	layer := r.m.ObjectGroups[i]
-	return r._renderObjectGroup(layer)
+	return r._renderObjectGroup(layer, r.layerState(layer))
 }
 
-func (r *Renderer) _renderObjectGroup(objectGroup *tiled.ObjectGroup) error {
-	objs := objectGroup.Objects
+func (r *Renderer) _renderObjectGroup(objectGroup *tiled.ObjectGroup, state *tiled.LayerState) error {
+	return r.composite(func() error {
+		return r.drawObjects(objectGroup, state)
+	})
+}
+
+func (r *Renderer) drawObjects(objectGroup *tiled.ObjectGroup, state *tiled.LayerState) error {
+	// Objects of the map are not reordered, as it can be rendered by several renderers at the same time
+	objs := append([]*tiled.Object(nil), objectGroup.Objects...)
 
 	// sort objects from left top to right down
 	objs = utils.SortAnySlice(objs, func(a, b *tiled.Object) bool {
@@ -127,7 +119,7 @@ func (r *Renderer) _renderObjectGroup(objectGroup *tiled.ObjectGroup) error {
 	})
 
 	for _, obj := range objs {
-		if err := r.renderOneObject(objectGroup, obj); err != nil {
+		if err := r.renderOneObject(objectGroup, obj, state); err != nil {
 			return err
 		}
 	}
@@ -147,16 +139,23 @@ func (r *Renderer) RenderGroupObjectGroup(groupID, objectGroupID int) error {
 	}
 
 	layer := group.ObjectGroups[objectGroupID]
-	return r._renderObjectGroup(layer)
+	return r._renderObjectGroup(layer, r.layerState(layer))
 }
 
-func (r *Renderer) renderOneObject(layer *tiled.ObjectGroup, o *tiled.Object) error {
+func (r *Renderer) renderOneObject(layer *tiled.ObjectGroup, o *tiled.Object, state *tiled.LayerState) error {
 	if !o.Visible {
 		return nil
 	}
 
 	if o.GID == 0 {
-		// TODO: o.GID == 0
+		if o.Text != nil {
+			return r.renderTextObject(layer, o, state)
+		}
+		r.renderShapeObject(layer, o, state)
+		return nil
+	}
+
+	if !r.objectInResult(o, state) {
 		return nil
 	}
 
@@ -173,30 +172,72 @@ func (r *Renderer) renderOneObject(layer *tiled.ObjectGroup, o *tiled.Object) er
 	bounds := img.Bounds()
 	srcSize := bounds.Size()
 	dstSize := image.Pt(int(o.Width), int(o.Height))
+	imgSize := fillSize(srcSize, dstSize, tile.Tileset.FillMode)
 
-	if !srcSize.Eq(dstSize) {
-		img = imaging.Resize(img, dstSize.X, dstSize.Y, imaging.NearestNeighbor)
+	if !srcSize.Eq(imgSize) {
+		img = imaging.Resize(img, imgSize.X, imgSize.Y, imaging.NearestNeighbor)
 	}
 
+	// The anchor is placed at the object position, images with preserved aspect ratio are centered in the object
+	anchorX, anchorY := r.objectAnchor(tile.Tileset.ObjectAlignment, dstSize)
+	anchorX -= float64(dstSize.X-imgSize.X) / 2
+	anchorY -= float64(dstSize.Y-imgSize.Y) / 2
+
 	var originPoint image.Point
 
-	img, originPoint = r._rotateObjectImage(img, o.Rotation)
+	img, originPoint = r._rotateObjectImage(img, o.Rotation, anchorX, anchorY)
 
+	x, y := r.pixelToScreenCoords(o.X, o.Y)
 	bounds = img.Bounds()
-	pos := bounds.Add(image.Pt(int(o.X), int(o.Y)).Sub(originPoint))
+	pos := bounds.Add(image.Pt(int(x), int(y)).Add(r.layerOrigin(state)).Sub(originPoint))
+	r.drawImage(pos, img, state)
 
-	if layer.Opacity < 1 {
-		mask := image.NewUniform(color.Alpha{uint8(layer.Opacity * 255)})
+	return nil
+}
 
-		draw.DrawMask(r.Result, pos, img, img.Bounds().Min, mask, mask.Bounds().Min, draw.Over)
-	} else {
-		draw.Draw(r.Result, pos, img, img.Bounds().Min, draw.Over)
+// objectAnchor returns the point of a tile object of the given size that is placed at the object position.
+// Unspecified alignment is bottom-left, or bottom in isometric maps.
+func (r *Renderer) objectAnchor(alignment tiled.ObjectAlignment, size image.Point) (x, y float64) {
+	w, h := float64(size.X), float64(size.Y)
+	switch alignment {
+	case tiled.ObjectAlignmentTopLeft:
+		return 0, 0
+	case tiled.ObjectAlignmentTop:
+		return w / 2, 0
+	case tiled.ObjectAlignmentTopRight:
+		return w, 0
+	case tiled.ObjectAlignmentLeft:
+		return 0, h / 2
+	case tiled.ObjectAlignmentCenter:
+		return w / 2, h / 2
+	case tiled.ObjectAlignmentRight:
+		return w, h / 2
+	case tiled.ObjectAlignmentBottomLeft:
+		return 0, h
+	case tiled.ObjectAlignmentBottom:
+		return w / 2, h
+	case tiled.ObjectAlignmentBottomRight:
+		return w, h
 	}
+	if r.m.Orientation == "isometric" {
+		return w / 2, h
+	}
+	return 0, h
+}
 
-	return nil
+// objectInResult reports whether the image of a tile or text object can intersect the result image.
+// The image is rotated around its anchor at the object position, so it is never farther from it than
+// the diagonal of the object.
+func (r *Renderer) objectInResult(o *tiled.Object, state *tiled.LayerState) bool {
+	x, y := r.pixelToScreenCoords(o.X, o.Y)
+	pos := image.Pt(int(x), int(y)).Add(r.layerOrigin(state))
+	reach := int(math.Ceil(math.Hypot(o.Width, o.Height))) + 1
+	return image.Rect(pos.X-reach, pos.Y-reach, pos.X+reach, pos.Y+reach).Overlaps(r.resultBounds())
 }
 
-func (r *Renderer) _rotateObjectImage(img image.Image, rotation float64) (newImage image.Image, originPoint image.Point) {
+// _rotateObjectImage rotates object image around the anchor point and returns the position
+// of the anchor point in the rotated image.
+func (r *Renderer) _rotateObjectImage(img image.Image, rotation, anchorX, anchorY float64) (newImage image.Image, originPoint image.Point) {
 	bounds := img.Bounds()
 	w := bounds.Dx()
 	h := bounds.Dy()
@@ -228,7 +269,10 @@ func (r *Renderer) _rotateObjectImage(img image.Image, rotation float64) (newIma
 		rotatedMinY = math.Min(rotatedMinY, rotatedPointsY[i])
 	}
 
-	originPoint = image.Pt(int(rotatedPointsX[3]-rotatedMinX), int(rotatedPointsY[3]-rotatedMinY))
+	rotatedAnchorX := anchorX*cos - anchorY*sin
+	rotatedAnchorY := anchorX*sin + anchorY*cos
+
+	originPoint = image.Pt(int(rotatedAnchorX-rotatedMinX), int(rotatedAnchorY-rotatedMinY))
 
 	return imaging.Rotate(img, -rotation, color.RGBA{}), originPoint
 }
@@ -21,9 +21,11 @@ package render
 
 import (
 	"os"
+	"sync"
 	"testing"
 
 	"github.com/lafriks/go-tiled"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestRenderer_RenderObjectGroup(t *testing.T) {
@@ -48,3 +50,34 @@ func TestRenderer_RenderObjectGroup(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestRenderObjectsConcurrently(t *testing.T) {
+	m, err := tiled.LoadFile("../assets/test_render_objects.tmx")
+	assert.NoError(t, err)
+
+	// Objects in reverse order are sorted when drawing them
+	objs := m.ObjectGroups[0].Objects
+	for i, j := 0, len(objs)-1; i < j; i, j = i+1, j-1 {
+		objs[i], objs[j] = objs[j], objs[i]
+	}
+	order := append([]*tiled.Object(nil), objs...)
+
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		r, err := NewRenderer(m)
+		assert.NoError(t, err)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			assert.NoError(t, r.RenderObjectGroup(0))
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	// Objects of the map keep their order
+	assert.Equal(t, order, m.ObjectGroups[0].Objects)
+}
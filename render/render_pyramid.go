@@ -0,0 +1,205 @@
+/*
+Copyright (c) 2023 Lauris Bukšis-Haberkorns <lauris@nix.lv>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package render
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// DefaultPyramidTileSize is the size of pyramid tiles used by web map viewers.
+const DefaultPyramidTileSize = 256
+
+// PyramidOptions are options of the tile pyramid.
+type PyramidOptions struct {
+	// Size of the square tiles in pixels. Defaults to DefaultPyramidTileSize.
+	TileSize int
+	// Lowest zoom level that is written. Defaults to 0, where the whole map fits a single tile.
+	MinZoom int
+}
+
+// TileSink receives the tiles of the tile pyramid.
+type TileSink interface {
+	WriteTile(z, x, y int, img image.Image) error
+}
+
+// TileSinkFunc is an adapter to allow the use of ordinary functions as tile sinks.
+type TileSinkFunc func(z, x, y int, img image.Image) error
+
+// WriteTile calls f(z, x, y, img).
+func (f TileSinkFunc) WriteTile(z, x, y int, img image.Image) error {
+	return f(z, x, y, img)
+}
+
+// FileCreator creates files by their slash separated names, for example in a directory or an archive.
+type FileCreator interface {
+	Create(name string) (io.WriteCloser, error)
+}
+
+// DirFileCreator creates files in the directory, including missing parent directories.
+type DirFileCreator string
+
+// Create creates the file in the directory.
+func (d DirFileCreator) Create(name string) (io.WriteCloser, error) {
+	fileName := filepath.Join(string(d), filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(fileName), 0o755); err != nil {
+		return nil, err
+	}
+	return os.Create(fileName)
+}
+
+// PNGTileSink writes tiles as PNG images to files named "z/x/y.png".
+type PNGTileSink struct {
+	Files FileCreator
+}
+
+// WriteTile writes the tile as PNG image.
+func (s *PNGTileSink) WriteTile(z, x, y int, img image.Image) error {
+	w, err := s.Files.Create(fmt.Sprintf("%d/%d/%d.png", z, x, y))
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(w, img); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// PyramidMaxZoom returns the zoom level at which the map image is not scaled, so it is the lowest
+// level where the map fits into a square of 2^zoom tiles of the given size.
+func (r *Renderer) PyramidMaxZoom(tileSize int) int {
+	size := r.imageBounds().Size()
+	zoom := 0
+	for tileSize<<zoom < size.X || tileSize<<zoom < size.Y {
+		zoom++
+	}
+	return zoom
+}
+
+// RenderPyramid renders all visible layers of the map as tile pyramid for zoomable map viewers, which
+// is written to the sink. At the highest zoom level the map image is not scaled, while each lower
+// level is downsampled by half. Tiles of the highest level are rendered separately and combined into
+// the lower levels as soon as they are done, so only images of the size of the tiles are allocated and
+// the whole map image is never kept in memory. Tiles which are completely outside of the map image are
+// not written.
+func (r *Renderer) RenderPyramid(sink TileSink, options *PyramidOptions) error {
+	p := &pyramid{
+		r:        r,
+		sink:     sink,
+		bounds:   r.imageBounds(),
+		tileSize: DefaultPyramidTileSize,
+	}
+	if options != nil {
+		if options.TileSize > 0 {
+			p.tileSize = options.TileSize
+		}
+		p.minZoom = options.MinZoom
+	}
+	p.maxZoom = r.PyramidMaxZoom(p.tileSize)
+
+	_, err := p.renderTile(0, 0, 0)
+	return err
+}
+
+type pyramid struct {
+	r        *Renderer
+	sink     TileSink
+	bounds   image.Rectangle
+	tileSize int
+	minZoom  int
+	maxZoom  int
+}
+
+// renderTile renders the tile with all tiles of higher levels within it and writes them to the sink.
+// It returns nil if the tile is outside of the map image.
+func (p *pyramid) renderTile(z, x, y int) (*image.NRGBA, error) {
+	size := p.tileSize << (p.maxZoom - z)
+	region := image.Rect(x*size, y*size, (x+1)*size, (y+1)*size)
+	if !region.Overlaps(p.bounds) {
+		return nil, nil
+	}
+
+	var img *image.NRGBA
+	if z == p.maxZoom {
+		img = newNRGBA(image.Rect(0, 0, p.tileSize, p.tileSize))
+		// Content outside of the map image is clipped, the same as when rendering the whole map
+		visible := region.Intersect(p.bounds)
+		dst := img.SubImage(visible.Sub(region.Min)).(draw.Image)
+		if err := p.r.RenderRegion(dst, visible); err != nil {
+			return nil, err
+		}
+	} else {
+		var children [4]*image.NRGBA
+		for i := range children {
+			child, err := p.renderTile(z+1, 2*x+i%2, 2*y+i/2)
+			if err != nil {
+				return nil, err
+			}
+			children[i] = child
+		}
+		img = p.downsample(children)
+	}
+
+	if z >= p.minZoom {
+		if err := p.sink.WriteTile(z, x, y, img); err != nil {
+			return nil, err
+		}
+	}
+	return img, nil
+}
+
+// downsample returns the image of the four child tiles, ordered by rows and nil outside of the map image,
+// scaled down by half. Each pixel is the average of the four pixels it covers, weighted by their alpha.
+func (p *pyramid) downsample(children [4]*image.NRGBA) *image.NRGBA {
+	img := newNRGBA(image.Rect(0, 0, p.tileSize, p.tileSize))
+	for y := 0; y < p.tileSize; y++ {
+		for x := 0; x < p.tileSize; x++ {
+			var r, g, b, a uint32
+			for i := 0; i < 4; i++ {
+				// Pixels of odd sized tiles can cover pixels of several children
+				cx, cy := 2*x+i%2, 2*y+i/2
+				child := children[cy/p.tileSize*2+cx/p.tileSize]
+				if child == nil {
+					continue
+				}
+				c := child.NRGBAAt(cx%p.tileSize, cy%p.tileSize)
+				r += uint32(c.R) * uint32(c.A)
+				g += uint32(c.G) * uint32(c.A)
+				b += uint32(c.B) * uint32(c.A)
+				a += uint32(c.A)
+			}
+			if a == 0 {
+				continue
+			}
+			img.SetNRGBA(x, y, color.NRGBA{uint8((r + a/2) / a), uint8((g + a/2) / a), uint8((b + a/2) / a), uint8((a + 2) / 4)})
+		}
+	}
+	return img
+}
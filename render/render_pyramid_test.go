@@ -0,0 +1,134 @@
+/*
+Copyright (c) 2023 Lauris Bukšis-Haberkorns <lauris@nix.lv>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"io"
+	"testing"
+
+	"github.com/disintegration/imaging"
+	"github.com/lafriks/go-tiled"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryFiles stores created files in memory.
+type memoryFiles map[string]*bytes.Buffer
+
+func (f memoryFiles) Create(name string) (io.WriteCloser, error) {
+	buf := &bytes.Buffer{}
+	f[name] = buf
+	return nopCloser{buf}, nil
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+func TestRenderPyramid(t *testing.T) {
+	m, err := tiled.LoadFile("../assets/test_render_objects.tmx")
+	assert.NoError(t, err)
+
+	r, err := NewRenderer(m)
+	assert.NoError(t, err)
+	assert.NoError(t, r.RenderVisibleMapLayers())
+	full := r.Result
+	assert.Equal(t, image.Rect(0, 0, 640, 640), full.Bounds())
+	assert.Equal(t, 2, r.PyramidMaxZoom(256))
+	assert.Equal(t, 0, r.PyramidMaxZoom(1024))
+
+	tiles := map[string]*image.NRGBA{}
+	sink := TileSinkFunc(func(z, x, y int, img image.Image) error {
+		tiles[fmt.Sprintf("%d/%d/%d", z, x, y)] = img.(*image.NRGBA)
+		return nil
+	})
+	assert.NoError(t, r.RenderPyramid(sink, nil))
+	assert.Len(t, tiles, 1+2*2+3*3)
+
+	// Tiles of the highest level are parts of the map image
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 3; x++ {
+			expected := image.NewNRGBA(image.Rect(0, 0, 256, 256))
+			draw.Draw(expected, expected.Bounds(), full, image.Pt(x*256, y*256), draw.Src)
+			assert.Equal(t, expected.Pix, tiles[fmt.Sprintf("2/%d/%d", x, y)].Pix, "tile %d,%d", x, y)
+		}
+	}
+
+	// Lower levels are downsampled by half
+	for _, z := range []int{0, 1} {
+		size := 640 >> (2 - z)
+		scaled := imaging.Resize(full, size, size, imaging.Box)
+		tile := tiles[fmt.Sprintf("%d/0/0", z)]
+		for y := 0; y < size && y < 256; y++ {
+			for x := 0; x < size && x < 256; x++ {
+				a, b := scaled.NRGBAAt(x, y), tile.NRGBAAt(x, y)
+				if !assert.InDelta(t, int(a.A), int(b.A), 2, "zoom %d pixel %d,%d", z, x, y) {
+					return
+				}
+			}
+		}
+		assert.Equal(t, uint8(0), tile.NRGBAAt(size, size).A)
+	}
+
+	// Lower levels can be skipped
+	files := memoryFiles{}
+	assert.NoError(t, r.RenderPyramid(&PNGTileSink{Files: files}, &PyramidOptions{TileSize: 512, MinZoom: 1}))
+	assert.Len(t, files, 2*2)
+	img, err := png.Decode(files["1/1/1.png"])
+	assert.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 512, 512), img.Bounds())
+}
+
+func TestRenderPyramidAllocations(t *testing.T) {
+	m, err := tiled.LoadFile("../assets/test_wangsets_map.tmx")
+	assert.NoError(t, err)
+
+	allocated := trackImages(t)
+	r, err := NewRenderer(m)
+	assert.NoError(t, err)
+
+	// Only images of single tiles are allocated, tiles of odd size are downsampled as well
+	for _, size := range []int{256, 255} {
+		*allocated = nil
+		tiles := 0
+		sink := TileSinkFunc(func(z, x, y int, img image.Image) error {
+			tiles++
+			return nil
+		})
+		assert.NoError(t, r.RenderPyramid(sink, &PyramidOptions{TileSize: size, MinZoom: 2}))
+		assert.NotZero(t, tiles)
+		assert.NotEmpty(t, *allocated)
+		for _, rect := range *allocated {
+			assert.Equal(t, image.Rect(0, 0, size, size), rect)
+		}
+	}
+	assert.Nil(t, r.Result)
+}
@@ -0,0 +1,187 @@
+/*
+Copyright (c) 2023 Lauris Bukšis-Haberkorns <lauris@nix.lv>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"github.com/disintegration/imaging"
+	"github.com/lafriks/go-tiled"
+	"github.com/lafriks/go-tiled/internal/utils"
+)
+
+// scale returns the scale factor of the rendered image.
+func (r *Renderer) scale() float64 {
+	if r.Scale <= 0 {
+		return 1
+	}
+	return r.Scale
+}
+
+// scaleTiles reports whether images are scaled when they are drawn instead of scaling the composite.
+func (r *Renderer) scaleTiles() bool {
+	return r.ScaleTiles && r.scale() != 1
+}
+
+// scaleRect returns the rectangle of the scaled image that covers the rectangle of the map image.
+// Edges are rounded down, so that adjacent rectangles stay adjacent without gaps between them.
+func (r *Renderer) scaleRect(rect image.Rectangle) image.Rectangle {
+	s := r.scale()
+	if s == 1 {
+		return rect
+	}
+	return image.Rect(
+		int(math.Floor(float64(rect.Min.X)*s)),
+		int(math.Floor(float64(rect.Min.Y)*s)),
+		int(math.Floor(float64(rect.Max.X)*s)),
+		int(math.Floor(float64(rect.Max.Y)*s)),
+	)
+}
+
+// unscaleRect returns the rectangle of the map image that covers the rectangle of the scaled image.
+func (r *Renderer) unscaleRect(rect image.Rectangle) image.Rectangle {
+	s := r.scale()
+	if s == 1 {
+		return rect
+	}
+	return image.Rect(
+		int(math.Floor(float64(rect.Min.X)/s)),
+		int(math.Floor(float64(rect.Min.Y)/s)),
+		int(math.Ceil(float64(rect.Max.X)/s)),
+		int(math.Ceil(float64(rect.Max.Y)/s)),
+	)
+}
+
+// imageBounds returns the bounds of the rendered image of the whole map.
+func (r *Renderer) imageBounds() image.Rectangle {
+	return r.scaleRect(r.engine.GetFinalImageSize())
+}
+
+// resultBounds returns the rectangle of the map image that is drawn to the result image.
+func (r *Renderer) resultBounds() image.Rectangle {
+	if r.scaleTiles() {
+		return r.unscaleRect(r.Result.Bounds())
+	}
+	return r.Result.Bounds()
+}
+
+// composite calls render to draw to the result image. When the composite is scaled, render draws
+// to an image of the original size instead, which is then scaled and drawn over the result.
+func (r *Renderer) composite(render func() error) error {
+	r.ensureResult()
+	s := r.scale()
+	if s == 1 || r.ScaleTiles {
+		return render()
+	}
+
+	scaled := r.Result
+	defer func() { r.Result = scaled }()
+
+	// Pixels around the edges are rendered as well, so that they are resampled the same way as in the
+	// middle of the image. The rendered rectangle is aligned to pixels where the grids of the scaled and
+	// the original image meet, so that separately rendered regions are resampled the same way and join
+	// without seams.
+	p, q := r.scaleRatio()
+	margin := int(math.Ceil(r.ScaleFilter.Support*math.Max(1, 1/s)*s)) + 1
+	outer := scaled.Bounds().Inset(-margin)
+	outer = image.Rect(utils.FloorDiv(outer.Min.X, p)*p, utils.FloorDiv(outer.Min.Y, p)*p, -utils.FloorDiv(-outer.Max.X, p)*p, -utils.FloorDiv(-outer.Max.Y, p)*p)
+	r.Result = newNRGBA(image.Rectangle{Min: outer.Min.Div(p).Mul(q), Max: outer.Max.Div(p).Mul(q)})
+	if err := render(); err != nil {
+		return err
+	}
+
+	img := imaging.Resize(r.Result, outer.Dx(), outer.Dy(), r.ScaleFilter)
+	draw.Draw(scaled, outer, img, image.Point{}, draw.Over)
+	return nil
+}
+
+// scaleRatio returns the scale factor as fraction p/q. Factors that can not be expressed with small
+// terms are approximated.
+func (r *Renderer) scaleRatio() (p, q int) {
+	s := r.scale()
+
+	// Convergents of the continued fraction of the scale factor
+	p, q = 1, 0
+	prevP, prevQ := 0, 1
+	x := s
+	for i := 0; i < 32; i++ {
+		a := int(math.Floor(x))
+		nextP, nextQ := a*p+prevP, a*q+prevQ
+		if nextP > 1000 || nextQ > 1000 {
+			break
+		}
+		p, q, prevP, prevQ = nextP, nextQ, p, q
+		if math.Abs(float64(p)/float64(q)-s) < 1e-9 || x == float64(a) {
+			break
+		}
+		x = 1 / (x - float64(a))
+	}
+	if p == 0 || q == 0 {
+		return 1, 1
+	}
+	return p, q
+}
+
+// drawImage draws the image to the rectangle of the map image with the opacity and tint color of a
+// layer with the given state. The image is resampled to fill the rectangle when their sizes differ,
+// which is also the case for the scaled rectangle when images are scaled as they are drawn.
+func (r *Renderer) drawImage(rect image.Rectangle, img image.Image, state *tiled.LayerState) {
+	if r.scaleTiles() {
+		rect = r.scaleRect(rect)
+	}
+	if rect.Empty() || !rect.Overlaps(r.Result.Bounds()) {
+		return
+	}
+	if !rect.Size().Eq(img.Bounds().Size()) {
+		img = imaging.Resize(img, rect.Dx(), rect.Dy(), r.ScaleFilter)
+	}
+
+	if state.TintColor != nil {
+		img = tintImage(img, state.TintColor.NRGBA())
+	}
+
+	if state.Opacity < 1 {
+		mask := image.NewUniform(color.Alpha{uint8(state.Opacity * 255)})
+
+		draw.DrawMask(r.Result, rect, img, img.Bounds().Min, mask, mask.Bounds().Min, draw.Over)
+	} else {
+		draw.Draw(r.Result, rect, img, img.Bounds().Min, draw.Over)
+	}
+}
+
+// tintImage returns copy of the image with colors multiplied by the tint color.
+func tintImage(img image.Image, t color.NRGBA) *image.NRGBA {
+	bounds := img.Bounds()
+	tinted := image.NewNRGBA(bounds)
+	draw.Draw(tinted, bounds, img, bounds.Min, draw.Src)
+	for i := 0; i < len(tinted.Pix); i += 4 {
+		tinted.Pix[i] = uint8((uint16(tinted.Pix[i])*uint16(t.R) + 127) / 255)
+		tinted.Pix[i+1] = uint8((uint16(tinted.Pix[i+1])*uint16(t.G) + 127) / 255)
+		tinted.Pix[i+2] = uint8((uint16(tinted.Pix[i+2])*uint16(t.B) + 127) / 255)
+		tinted.Pix[i+3] = uint8((uint16(tinted.Pix[i+3])*uint16(t.A) + 127) / 255)
+	}
+	return tinted
+}
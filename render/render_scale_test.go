@@ -0,0 +1,161 @@
+/*
+Copyright (c) 2023 Lauris Bukšis-Haberkorns <lauris@nix.lv>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+
+	"github.com/disintegration/imaging"
+	"github.com/lafriks/go-tiled"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderScaledUp(t *testing.T) {
+	for _, name := range []string{"test_render_order.tmx", "test_render_offsets.tmx", "test_isometric.tmx"} {
+		m, err := tiled.LoadFile("../assets/" + name)
+		assert.NoError(t, err)
+
+		// Shape outlines are drawn with the same width when the shapes are scaled
+		r, err := NewRenderer(m)
+		assert.NoError(t, err)
+		r.ShapeStyle = ShapeStyle{FillColor: color.NRGBA{0, 0, 255, 255}}
+		assert.NoError(t, r.RenderVisibleMapLayers())
+		size := r.Result.Bounds().Size()
+		expected := imaging.Resize(r.Result, size.X*3, size.Y*3, imaging.NearestNeighbor)
+
+		// Integer scaling with nearest-neighbor filter gives the same result in both modes
+		for _, perTile := range []bool{false, true} {
+			r.Clear()
+			r.Scale = 3
+			r.ScaleTiles = perTile
+			assert.NoError(t, r.RenderVisibleMapLayers())
+			assert.Equal(t, expected.Bounds(), r.Result.Bounds())
+			assert.Equal(t, expected.Pix, r.Result.Pix, "%s per tile %v", name, perTile)
+		}
+	}
+}
+
+func TestRenderScaledDown(t *testing.T) {
+	m, err := tiled.LoadFile("../assets/test_wangsets_map.tmx")
+	assert.NoError(t, err)
+
+	r, err := NewRenderer(m)
+	assert.NoError(t, err)
+	assert.NoError(t, r.RenderLayer(0))
+	expected := imaging.Resize(r.Result, 800, 800, imaging.Box)
+
+	r.Clear()
+	r.Scale = 0.5
+	r.ScaleFilter = imaging.Box
+	assert.NoError(t, r.RenderLayer(0))
+	assert.Equal(t, image.Rect(0, 0, 800, 800), r.Result.Bounds())
+	assert.Equal(t, image.Rect(0, 0, 800, 800), r.LayerBounds(m.Layers[0]))
+	assert.Equal(t, image.Rect(16, 0, 48, 16), r.TileRegion(image.Rect(1, 0, 3, 1)))
+	for y := 0; y < 800; y++ {
+		for x := 0; x < 800; x++ {
+			if !assert.Equal(t, expected.NRGBAAt(x, y), r.Result.NRGBAAt(x, y), "pixel %d,%d", x, y) {
+				return
+			}
+		}
+	}
+}
+
+func TestRenderScaledAfterClear(t *testing.T) {
+	m, err := tiled.LoadFile("../assets/test_wangsets_map.tmx")
+	assert.NoError(t, err)
+
+	r, err := NewRenderer(m)
+	assert.NoError(t, err)
+	assert.Nil(t, r.Result)
+	assert.Equal(t, image.Rect(0, 0, 1600, 1600), r.ResultImage().Bounds())
+
+	// Cleared result is allocated again with the scaled size
+	r.Clear()
+	assert.Nil(t, r.Result)
+	r.Scale = 0.5
+	assert.NoError(t, r.RenderLayer(0))
+	assert.Equal(t, image.Rect(0, 0, 800, 800), r.Result.Bounds())
+
+	// Rendered result is kept
+	result := r.Result
+	r.Scale = 0.25
+	assert.NoError(t, r.RenderLayer(0))
+	assert.Same(t, result, r.Result)
+}
+
+func TestRenderScaledWithoutSeams(t *testing.T) {
+	m, err := tiled.LoadFile("../assets/test_wangsets_map.tmx")
+	assert.NoError(t, err)
+
+	r, err := NewRenderer(m)
+	assert.NoError(t, err)
+	r.Scale = 0.5
+	r.ScaleFilter = imaging.Lanczos
+	assert.NoError(t, r.RenderVisibleMapLayers())
+	full := r.Result
+
+	// Regions are resampled together with their surroundings, so they join seamlessly
+	for _, region := range []image.Rectangle{image.Rect(0, 0, 800, 301), image.Rect(0, 301, 800, 800)} {
+		dst := image.NewNRGBA(region)
+		assert.NoError(t, r.RenderRegion(dst, region))
+		expected := image.NewNRGBA(region)
+		draw.Draw(expected, region, full, region.Min, draw.Src)
+		assert.Equal(t, expected.Pix, dst.Pix, "region %v", region)
+	}
+
+	// Tiles scaled as they are drawn cover the whole map without gaps
+	r.Clear()
+	r.Scale = 0.3
+	r.ScaleTiles = true
+	assert.NoError(t, r.RenderLayer(0))
+	assert.Equal(t, image.Rect(0, 0, 480, 480), r.Result.Bounds())
+	for y := 0; y < 480; y++ {
+		for x := 0; x < 480; x++ {
+			if !assert.Equal(t, uint8(255), r.Result.NRGBAAt(x, y).A, "pixel %d,%d", x, y) {
+				return
+			}
+		}
+	}
+}
+
+func TestScaleRatio(t *testing.T) {
+	tests := []struct {
+		scale float64
+		p, q  int
+	}{
+		{0, 1, 1},
+		{0.5, 1, 2},
+		{3, 3, 1},
+		{0.3, 3, 10},
+		{1.0 / 3, 1, 3},
+		{0.125, 1, 8},
+	}
+	for _, tt := range tests {
+		r := &Renderer{Scale: tt.scale}
+		p, q := r.scaleRatio()
+		assert.Equal(t, []int{tt.p, tt.q}, []int{p, q}, "scale %v", tt.scale)
+	}
+}
@@ -0,0 +1,262 @@
+/*
+Copyright (c) 2023 Lauris Bukšis-Haberkorns <lauris@nix.lv>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package render
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/lafriks/go-tiled"
+	"golang.org/x/image/vector"
+)
+
+// DefaultObjectColor is the color of objects in object groups without a color, same as in Tiled.
+var DefaultObjectColor = color.NRGBA{R: 0xa0, G: 0xa0, B: 0xa4, A: 0xff}
+
+// ShapeStyle describes how rectangle, ellipse, polygon, polyline and point objects are drawn.
+type ShapeStyle struct {
+	// Color of the shape outlines. If nil, the color of the object group is used, or DefaultObjectColor
+	// if the object group has no color.
+	StrokeColor color.Color
+	// Color the closed shapes are filled with. If nil, the outline color with FillOpacity is used.
+	FillColor color.Color
+	// Opacity of the outline color when it is used to fill the shapes.
+	FillOpacity float64
+	// Width of the outlines in pixels. Outlines are not drawn if it is zero.
+	StrokeWidth float64
+	// Radius in pixels of the circle drawn for point objects.
+	PointRadius float64
+}
+
+// DefaultShapeStyle is the shape style of new renderers. Similar to Tiled, shapes are filled with
+// a translucent object color.
+var DefaultShapeStyle = ShapeStyle{
+	FillOpacity: 50.0 / 255,
+	StrokeWidth: 1,
+	PointRadius: 4,
+}
+
+// ellipseSegments is the number of segments ellipses are approximated with.
+const ellipseSegments = 64
+
+type vec struct {
+	x, y float64
+}
+
+func (r *Renderer) renderShapeObject(layer *tiled.ObjectGroup, o *tiled.Object, state *tiled.LayerState) {
+	style := r.ShapeStyle
+	stroke := style.StrokeColor
+	if stroke == nil {
+		stroke = DefaultObjectColor
+		if layer.Color != nil {
+			stroke = layer.Color
+		}
+	}
+	fill := style.FillColor
+	if fill == nil {
+		fill = withOpacity(stroke, style.FillOpacity)
+	}
+	stroke = withOpacity(withTint(stroke, state.TintColor), float64(state.Opacity))
+	fill = withOpacity(withTint(fill, state.TintColor), float64(state.Opacity))
+
+	if len(o.PointMarkers) > 0 {
+		// Points keep their size regardless of the map orientation
+		center := r.objectToScreen(o, []vec{{}}, r.layerOrigin(state))[0]
+		r.fillPaths([][]vec{ellipse(center, style.PointRadius, style.PointRadius)}, stroke)
+		return
+	}
+
+	outline, closed := objectOutline(o)
+	if len(outline) == 0 {
+		return
+	}
+	outline = r.objectToScreen(o, outline, r.layerOrigin(state))
+
+	if closed {
+		r.fillPaths([][]vec{outline}, fill)
+	}
+	if style.StrokeWidth > 0 {
+		r.fillPaths(strokePaths(outline, closed, style.StrokeWidth), stroke)
+	}
+}
+
+// objectOutline returns the outline of the object shape relative to the object position, before
+// rotation, and whether the shape is closed.
+func objectOutline(o *tiled.Object) ([]vec, bool) {
+	switch {
+	case len(o.Ellipses) > 0:
+		return ellipse(vec{o.Width / 2, o.Height / 2}, o.Width/2, o.Height/2), true
+	case len(o.Polygons) > 0:
+		return pointsToVecs(o.Polygons[0].Points), true
+	case len(o.PolyLines) > 0:
+		return pointsToVecs(o.PolyLines[0].Points), false
+	case o.Width > 0 || o.Height > 0:
+		return []vec{{0, 0}, {o.Width, 0}, {o.Width, o.Height}, {0, o.Height}}, true
+	}
+	return nil, false
+}
+
+func pointsToVecs(points *tiled.Points) []vec {
+	if points == nil {
+		return nil
+	}
+	path := make([]vec, len(*points))
+	for i, p := range *points {
+		path[i] = vec{p.X, p.Y}
+	}
+	return path
+}
+
+// objectToScreen rotates points around the object position and converts them to coordinates in the result
+// image, with the map pixel origin at the given position.
+func (r *Renderer) objectToScreen(o *tiled.Object, points []vec, origin image.Point) []vec {
+	sin, cos := math.Sincos(o.Rotation * math.Pi / 180)
+	screen := make([]vec, len(points))
+	for i, p := range points {
+		x, y := r.pixelToScreenCoords(o.X+p.x*cos-p.y*sin, o.Y+p.x*sin+p.y*cos)
+		screen[i] = vec{x + float64(origin.X), y + float64(origin.Y)}
+	}
+	return screen
+}
+
+// ellipse returns polygon approximating the ellipse.
+func ellipse(center vec, rx, ry float64) []vec {
+	path := make([]vec, ellipseSegments)
+	for i := range path {
+		sin, cos := math.Sincos(2 * math.Pi * float64(i) / ellipseSegments)
+		path[i] = vec{center.x + rx*cos, center.y + ry*sin}
+	}
+	return path
+}
+
+// strokePaths returns polygons covering the outline of the path drawn with the given width, using round joins.
+func strokePaths(path []vec, closed bool, width float64) [][]vec {
+	hw := width / 2
+	var paths [][]vec
+	for i, p := range path {
+		paths = append(paths, ellipse(p, hw, hw))
+
+		if i == len(path)-1 && !closed {
+			break
+		}
+		q := path[(i+1)%len(path)]
+		dx, dy := q.x-p.x, q.y-p.y
+		l := math.Hypot(dx, dy)
+		if l == 0 {
+			continue
+		}
+		nx, ny := -dy/l*hw, dx/l*hw
+		paths = append(paths, []vec{{p.x + nx, p.y + ny}, {q.x + nx, q.y + ny}, {q.x - nx, q.y - ny}, {p.x - nx, p.y - ny}})
+	}
+	return paths
+}
+
+// fillPaths fills the union of closed paths with anti-aliasing. Only the part of the result
+// covered by the paths is rasterized.
+func (r *Renderer) fillPaths(paths [][]vec, c color.Color) {
+	if r.scaleTiles() {
+		paths = scalePaths(paths, r.scale())
+	}
+
+	min := vec{math.Inf(1), math.Inf(1)}
+	max := vec{math.Inf(-1), math.Inf(-1)}
+	for _, path := range paths {
+		for _, p := range path {
+			min = vec{math.Min(min.x, p.x), math.Min(min.y, p.y)}
+			max = vec{math.Max(max.x, p.x), math.Max(max.y, p.y)}
+		}
+	}
+	bounds := image.Rect(int(math.Floor(min.x)), int(math.Floor(min.y)), int(math.Ceil(max.x)), int(math.Ceil(max.y)))
+	bounds = bounds.Intersect(r.Result.Bounds())
+	if bounds.Empty() {
+		return
+	}
+
+	z := vector.NewRasterizer(bounds.Dx(), bounds.Dy())
+	for _, path := range paths {
+		if len(path) < 3 {
+			continue
+		}
+		// Overlapping paths are only merged by the rasterizer if they have the same winding
+		if signedArea(path) < 0 {
+			path = reversed(path)
+		}
+		z.MoveTo(float32(path[0].x-float64(bounds.Min.X)), float32(path[0].y-float64(bounds.Min.Y)))
+		for _, p := range path[1:] {
+			z.LineTo(float32(p.x-float64(bounds.Min.X)), float32(p.y-float64(bounds.Min.Y)))
+		}
+		z.ClosePath()
+	}
+	z.Draw(r.Result, bounds, image.NewUniform(c), image.Point{})
+}
+
+func scalePaths(paths [][]vec, s float64) [][]vec {
+	scaled := make([][]vec, len(paths))
+	for i, path := range paths {
+		scaled[i] = make([]vec, len(path))
+		for j, p := range path {
+			scaled[i][j] = vec{p.x * s, p.y * s}
+		}
+	}
+	return scaled
+}
+
+func signedArea(path []vec) float64 {
+	area := 0.0
+	for i, p := range path {
+		q := path[(i+1)%len(path)]
+		area += p.x*q.y - q.x*p.y
+	}
+	return area / 2
+}
+
+func reversed(path []vec) []vec {
+	res := make([]vec, len(path))
+	for i, p := range path {
+		res[len(path)-1-i] = p
+	}
+	return res
+}
+
+// withTint returns the color with its channels multiplied by the tint color, if it is not nil.
+func withTint(c color.Color, tint *tiled.HexColor) color.Color {
+	if tint == nil {
+		return c
+	}
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	t := tint.NRGBA()
+	return color.NRGBA{
+		R: uint8((uint16(n.R)*uint16(t.R) + 127) / 255),
+		G: uint8((uint16(n.G)*uint16(t.G) + 127) / 255),
+		B: uint8((uint16(n.B)*uint16(t.B) + 127) / 255),
+		A: uint8((uint16(n.A)*uint16(t.A) + 127) / 255),
+	}
+}
+
+// withOpacity returns the color with its alpha multiplied by the opacity.
+func withOpacity(c color.Color, opacity float64) color.Color {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	n.A = uint8(float64(n.A) * opacity)
+	return n
+}
@@ -0,0 +1,95 @@
+/*
+Copyright (c) 2023 Lauris Bukšis-Haberkorns <lauris@nix.lv>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package render
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/lafriks/go-tiled"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderShapes(t *testing.T) {
+	m, err := tiled.LoadFile("../assets/test_render_shapes.tmx")
+	assert.NoError(t, err)
+	assert.Len(t, m.ObjectGroups[0].Objects[4].PointMarkers, 1)
+
+	r, err := NewRenderer(m)
+	assert.NoError(t, err)
+	assert.NoError(t, r.RenderVisibleMapLayers())
+
+	// Shapes are filled with translucent group color and outlined with it
+	fill := r.Result.NRGBAAt(16, 12)
+	assert.Equal(t, uint8(255), fill.R)
+	assert.Equal(t, uint8(0), fill.G)
+	assert.Greater(t, fill.A, uint8(0))
+	assert.Less(t, fill.A, uint8(128))
+	assert.Greater(t, r.Result.NRGBAAt(16, 8).A, fill.A)
+	assert.Equal(t, color.NRGBA{}, r.Result.NRGBAAt(16, 4))
+
+	// Polylines are only outlined
+	assert.Greater(t, r.Result.NRGBAAt(48, 48).A, uint8(0))
+	assert.Equal(t, color.NRGBA{}, r.Result.NRGBAAt(44, 52))
+
+	assert.Equal(t, color.NRGBA{255, 0, 0, 255}, r.Result.NRGBAAt(56, 56))
+}
+
+func TestRenderShapesStyle(t *testing.T) {
+	m, err := tiled.LoadFile("../assets/test_render_shapes.tmx")
+	assert.NoError(t, err)
+
+	blue := color.NRGBA{0, 0, 255, 255}
+	r, err := NewRenderer(m)
+	assert.NoError(t, err)
+	r.ShapeStyle = ShapeStyle{FillColor: blue, PointRadius: 2}
+	assert.NoError(t, r.RenderObjectGroup(0))
+
+	tests := []struct {
+		name  string
+		x, y  int
+		color color.NRGBA
+	}{
+		{"rectangle top-left", 8, 8, blue},
+		{"rectangle bottom-right", 23, 15, blue},
+		{"left of rectangle", 7, 8, color.NRGBA{}},
+		{"right of rectangle", 24, 8, color.NRGBA{}},
+		{"ellipse center", 40, 8, blue},
+		{"ellipse corner", 32, 0, color.NRGBA{}},
+		{"polygon", 9, 41, blue},
+		{"outside of polygon", 22, 54, color.NRGBA{}},
+		{"polyline", 48, 48, color.NRGBA{}},
+		{"point", 56, 56, color.NRGBA{255, 0, 0, 255}},
+		{"outside of point", 59, 59, color.NRGBA{}},
+		{"rotated rectangle", 36, 24, blue},
+		{"outside of rotated rectangle", 44, 20, color.NRGBA{}},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.color, r.Result.NRGBAAt(tt.x, tt.y), tt.name)
+	}
+
+	m.ObjectGroups[0].Opacity = 0.5
+	r.Clear()
+	assert.NoError(t, r.RenderObjectGroup(0))
+	assert.Equal(t, color.NRGBA{0, 0, 255, 127}, r.Result.NRGBAAt(16, 12))
+}
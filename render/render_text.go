@@ -0,0 +1,282 @@
+/*
+Copyright (c) 2023 Lauris Bukšis-Haberkorns <lauris@nix.lv>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strings"
+	"sync"
+
+	"github.com/lafriks/go-tiled"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/gobolditalic"
+	"golang.org/x/image/font/gofont/goitalic"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// FontResolver provides font faces used to render text objects.
+type FontResolver interface {
+	// ResolveFont returns font face for the font family, pixel size, boldness and italic style of the text.
+	ResolveFont(text *tiled.Text) (font.Face, error)
+}
+
+// FontResolverFunc is an adapter to allow the use of ordinary functions as font resolvers.
+type FontResolverFunc func(text *tiled.Text) (font.Face, error)
+
+// ResolveFont calls f(text).
+func (f FontResolverFunc) ResolveFont(text *tiled.Text) (font.Face, error) {
+	return f(text)
+}
+
+type goFontStyle struct {
+	bold, italic bool
+}
+
+type goFontFace struct {
+	goFontStyle
+	size int
+}
+
+// GoFontResolver resolves fonts of all families to the embedded Go fonts. Custom font resolvers can
+// use it as a fallback for families they do not provide. It is safe for concurrent use, but the
+// returned faces are not, so renderers drawing text at the same time should use separate resolvers.
+type GoFontResolver struct {
+	mu    sync.Mutex
+	fonts map[goFontStyle]*opentype.Font
+	faces map[goFontFace]font.Face
+}
+
+// ResolveFont returns Go font face for the pixel size, boldness and italic style of the text.
+func (res *GoFontResolver) ResolveFont(text *tiled.Text) (font.Face, error) {
+	key := goFontFace{goFontStyle{text.Bold, text.Italic}, text.Size}
+
+	res.mu.Lock()
+	defer res.mu.Unlock()
+	if face, ok := res.faces[key]; ok {
+		return face, nil
+	}
+
+	f, ok := res.fonts[key.goFontStyle]
+	if !ok {
+		ttf := goregular.TTF
+		switch {
+		case text.Bold && text.Italic:
+			ttf = gobolditalic.TTF
+		case text.Bold:
+			ttf = gobold.TTF
+		case text.Italic:
+			ttf = goitalic.TTF
+		}
+
+		var err error
+		if f, err = opentype.Parse(ttf); err != nil {
+			return nil, err
+		}
+		if res.fonts == nil {
+			res.fonts = make(map[goFontStyle]*opentype.Font)
+		}
+		res.fonts[key.goFontStyle] = f
+	}
+
+	// Sizes are in pixels, so 72 DPI is used to have one pixel per point
+	face, err := opentype.NewFace(f, &opentype.FaceOptions{
+		Size:    float64(text.Size),
+		DPI:     72,
+		Hinting: font.HintingFull,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if res.faces == nil {
+		res.faces = make(map[goFontFace]font.Face)
+	}
+	res.faces[key] = face
+	return face, nil
+}
+
+func (r *Renderer) renderTextObject(layer *tiled.ObjectGroup, o *tiled.Object, state *tiled.LayerState) error {
+	w, h := int(math.Ceil(o.Width)), int(math.Ceil(o.Height))
+	if w <= 0 || h <= 0 || len(o.Text.Text) == 0 || !r.objectInResult(o, state) {
+		return nil
+	}
+
+	face, err := r.FontResolver.ResolveFont(o.Text)
+	if err != nil {
+		return err
+	}
+
+	// Text is laid out inside the object box, which is then rotated and placed the same way as tile objects
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	drawText(img, face, o.Text)
+
+	rotated, originPoint := r._rotateObjectImage(img, o.Rotation, 0, 0)
+
+	x, y := r.pixelToScreenCoords(o.X, o.Y)
+	pos := rotated.Bounds().Add(image.Pt(int(x), int(y)).Add(r.layerOrigin(state)).Sub(originPoint))
+	r.drawImage(pos, rotated, state)
+
+	return nil
+}
+
+// textLine is a single line of laid out text.
+type textLine struct {
+	text  string
+	width fixed.Int26_6
+	// Set for the last line of a paragraph, which is never justified
+	last bool
+}
+
+// drawText draws the text inside of the image bounds honouring wrapping and alignment.
+func drawText(dst draw.Image, face font.Face, text *tiled.Text) {
+	bounds := dst.Bounds()
+	maxWidth := fixed.I(bounds.Dx())
+	lines := layoutText(face, text, maxWidth)
+
+	// Zero color is the default color of the parsed text, which is black in Tiled
+	var c color.Color = color.Black
+	if text.Color != nil && *text.Color != (tiled.HexColor{}) {
+		c = text.Color
+	}
+	src := image.NewUniform(c)
+
+	metrics := face.Metrics()
+	lineHeight := metrics.Height
+	y := fixed.I(bounds.Min.Y)
+	switch text.VAlign {
+	case "center":
+		y += (fixed.I(bounds.Dy()) - lineHeight*fixed.Int26_6(len(lines))) / 2
+	case "bottom":
+		y += fixed.I(bounds.Dy()) - lineHeight*fixed.Int26_6(len(lines))
+	}
+
+	thickness := text.Size / 16
+	if thickness < 1 {
+		thickness = 1
+	}
+
+	for _, line := range lines {
+		x := fixed.I(bounds.Min.X)
+		var spacing fixed.Int26_6
+		switch text.HAlign {
+		case "center":
+			x += (maxWidth - line.width) / 2
+		case "right":
+			x += maxWidth - line.width
+		case "justify":
+			if spaces := strings.Count(line.text, " "); text.Wrap && !line.last && spaces > 0 {
+				spacing = (maxWidth - line.width) / fixed.Int26_6(spaces)
+			}
+		}
+
+		baseline := y + metrics.Ascent
+		width := drawString(dst, face, src, fixed.Point26_6{X: x, Y: baseline}, line.text, text.Kerning, spacing)
+
+		decoration := func(top int) {
+			rect := image.Rect(x.Round(), top, (x + width).Round(), top+thickness)
+			draw.Draw(dst, rect, src, image.Point{}, draw.Over)
+		}
+		if text.Underline {
+			decoration(baseline.Round() + thickness)
+		}
+		if text.Strikethrough {
+			strike := metrics.XHeight / 2
+			if strike == 0 {
+				strike = metrics.Ascent * 3 / 10
+			}
+			decoration((baseline - strike).Round() - thickness/2)
+		}
+
+		y += lineHeight
+	}
+}
+
+// layoutText splits the text into lines, wrapping words that do not fit the maximum width if wrapping is enabled.
+func layoutText(face font.Face, text *tiled.Text, maxWidth fixed.Int26_6) []textLine {
+	var lines []textLine
+	for _, paragraph := range strings.Split(text.Text, "\n") {
+		paragraph = strings.TrimRight(paragraph, "\r")
+		if !text.Wrap {
+			lines = append(lines, textLine{text: paragraph, width: measureString(face, paragraph, text.Kerning), last: true})
+			continue
+		}
+
+		var line textLine
+		for i, word := range strings.Split(paragraph, " ") {
+			candidate := word
+			if i > 0 {
+				candidate = line.text + " " + word
+			}
+			width := measureString(face, candidate, text.Kerning)
+			if i > 0 && width > maxWidth {
+				lines = append(lines, line)
+				candidate = word
+				width = measureString(face, word, text.Kerning)
+			}
+			line = textLine{text: candidate, width: width}
+		}
+		line.last = true
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func measureString(face font.Face, s string, kerning bool) fixed.Int26_6 {
+	var width fixed.Int26_6
+	prev := rune(-1)
+	for _, c := range s {
+		if kerning && prev >= 0 {
+			width += face.Kern(prev, c)
+		}
+		advance, _ := face.GlyphAdvance(c)
+		width += advance
+		prev = c
+	}
+	return width
+}
+
+// drawString draws the string starting at the dot and returns its width. The spacing is added to every space character.
+func drawString(dst draw.Image, face font.Face, src image.Image, dot fixed.Point26_6, s string, kerning bool, spacing fixed.Int26_6) fixed.Int26_6 {
+	start := dot.X
+	prev := rune(-1)
+	for _, c := range s {
+		if kerning && prev >= 0 {
+			dot.X += face.Kern(prev, c)
+		}
+		dr, mask, maskp, advance, ok := face.Glyph(dot, c)
+		if ok {
+			draw.DrawMask(dst, dr, src, image.Point{}, mask, maskp, draw.Over)
+		}
+		dot.X += advance
+		if c == ' ' {
+			dot.X += spacing
+		}
+		prev = c
+	}
+	return dot.X - start
+}
@@ -0,0 +1,170 @@
+/*
+Copyright (c) 2023 Lauris Bukšis-Haberkorns <lauris@nix.lv>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package render
+
+import (
+	"image"
+	"sync"
+	"testing"
+
+	"github.com/lafriks/go-tiled"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+)
+
+// inkBounds returns bounds of the drawn pixels of the result inside the rectangle.
+func inkBounds(r *Renderer, rect image.Rectangle) image.Rectangle {
+	var ink image.Rectangle
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			if r.Result.NRGBAAt(x, y).A > 0 {
+				ink = ink.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	return ink
+}
+
+func TestRenderText(t *testing.T) {
+	m, err := tiled.LoadFile("../assets/test_render_text.tmx")
+	assert.NoError(t, err)
+
+	r, err := NewRenderer(m)
+	assert.NoError(t, err)
+	assert.NoError(t, r.RenderVisibleMapLayers())
+
+	// Left and top aligned
+	left := inkBounds(r, image.Rect(0, 0, 128, 24))
+	assert.False(t, left.Empty())
+	assert.Less(t, left.Min.X, 4)
+	assert.Less(t, left.Max.X, 64)
+	assert.Less(t, left.Max.Y, 20)
+	for y := left.Min.Y; y < left.Max.Y; y++ {
+		for x := left.Min.X; x < left.Max.X; x++ {
+			if c := r.Result.NRGBAAt(x, y); c.A > 0 {
+				assert.Equal(t, []uint8{255, 0, 0}, []uint8{c.R, c.G, c.B})
+			}
+		}
+	}
+
+	// Right aligned text has the same width and default black color
+	right := inkBounds(r, image.Rect(0, 24, 128, 48))
+	assert.Greater(t, right.Max.X, 124)
+	assert.Equal(t, left.Dx(), right.Dx())
+	c := r.Result.NRGBAAt(right.Min.X+right.Dx()/2, right.Min.Y+right.Dy()/2)
+	assert.Equal(t, []uint8{0, 0, 0}, []uint8{c.R, c.G, c.B})
+
+	// Centered in both directions
+	centered := inkBounds(r, image.Rect(0, 48, 128, 80))
+	assert.InDelta(t, 64, (centered.Min.X+centered.Max.X)/2, 3)
+	assert.InDelta(t, 64, (centered.Min.Y+centered.Max.Y)/2, 4)
+
+	// Words that do not fit are wrapped to the next line
+	wrapped := inkBounds(r, image.Rect(0, 80, 64, 128))
+	assert.Greater(t, wrapped.Dy(), 24)
+	assert.LessOrEqual(t, wrapped.Max.X, 64)
+}
+
+func TestRenderTextDecorations(t *testing.T) {
+	m, err := tiled.LoadFile("../assets/test_render_text.tmx")
+	assert.NoError(t, err)
+
+	plain, err := NewRenderer(m)
+	assert.NoError(t, err)
+	assert.NoError(t, plain.RenderObjectGroup(0))
+
+	text := m.ObjectGroups[0].Objects[0].Text
+	text.Underline = true
+	underlined, err := NewRenderer(m)
+	assert.NoError(t, err)
+	assert.NoError(t, underlined.RenderObjectGroup(0))
+
+	// Underline is a continuous line below the text
+	rect := image.Rect(0, 0, 128, 24)
+	before, after := inkBounds(plain, rect), inkBounds(underlined, rect)
+	assert.Greater(t, after.Max.Y, before.Max.Y)
+	for x := after.Min.X + 1; x < after.Max.X-1; x++ {
+		assert.Greater(t, underlined.Result.NRGBAAt(x, after.Max.Y-1).A, uint8(0))
+	}
+}
+
+func TestFontResolver(t *testing.T) {
+	m, err := tiled.LoadFile("../assets/test_render_text.tmx")
+	assert.NoError(t, err)
+
+	r, err := NewRenderer(m)
+	assert.NoError(t, err)
+
+	var resolved []string
+	r.FontResolver = FontResolverFunc(func(text *tiled.Text) (font.Face, error) {
+		resolved = append(resolved, text.FontFamily)
+		return basicfont.Face7x13, nil
+	})
+	assert.NoError(t, r.RenderObjectGroup(0))
+	assert.Equal(t, []string{"sans-serif", "sans-serif", "sans-serif", "sans-serif"}, resolved)
+
+	// "Hello" is 5 characters of 7 pixels
+	right := inkBounds(r, image.Rect(0, 24, 128, 48))
+	assert.LessOrEqual(t, right.Dx(), 35)
+	assert.Greater(t, right.Max.X, 121)
+}
+
+func TestGoFontResolver(t *testing.T) {
+	res := &GoFontResolver{}
+	regular, err := res.ResolveFont(&tiled.Text{Size: 16})
+	assert.NoError(t, err)
+	bold, err := res.ResolveFont(&tiled.Text{Size: 16, Bold: true})
+	assert.NoError(t, err)
+	assert.NotSame(t, regular, bold)
+
+	again, err := res.ResolveFont(&tiled.Text{Size: 16, FontFamily: "serif"})
+	assert.NoError(t, err)
+	assert.Same(t, regular, again)
+
+	small, err := res.ResolveFont(&tiled.Text{Size: 8})
+	assert.NoError(t, err)
+	assert.Less(t, small.Metrics().Height, regular.Metrics().Height)
+}
+
+func TestGoFontResolverConcurrent(t *testing.T) {
+	res := &GoFontResolver{}
+	faces := make([]font.Face, 8)
+
+	var wg sync.WaitGroup
+	for i := range faces {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			face, err := res.ResolveFont(&tiled.Text{Size: 12, Italic: i%2 == 0})
+			assert.NoError(t, err)
+			faces[i] = face
+		}(i)
+	}
+	wg.Wait()
+
+	// Faces of the same style are created only once
+	for i := 2; i < len(faces); i++ {
+		assert.Same(t, faces[i%2], faces[i])
+	}
+}
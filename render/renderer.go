@@ -25,17 +25,21 @@ package render
 import (
 	"errors"
 	"image"
-	"image/color"
 	"image/draw"
 	"image/gif"
 	"image/jpeg"
 	"image/png"
 	"io"
 	"io/fs"
+	"math"
 	"os"
+	"runtime"
+	"sync"
+	"time"
 
 	"github.com/disintegration/imaging"
 	"github.com/lafriks/go-tiled"
+	"github.com/lafriks/go-tiled/internal/utils"
 )
 
 var (
@@ -56,15 +60,49 @@ type RendererEngine interface {
 	GetTilePosition(x, y int) image.Rectangle
 }
 
+// ScreenCoordsEngine is optionally implemented by rendering engines that place objects at positions in
+// the map image other than their pixel coordinates, for example in isometric maps. Objects are placed
+// at their pixel coordinates by engines that don't implement it.
+type ScreenCoordsEngine interface {
+	PixelToScreenCoords(x, y float64) (float64, float64)
+}
+
 // Renderer represents an rendering engine.
 type Renderer struct {
-	m         *tiled.Map
-	Result    *image.NRGBA // The image result after rendering using the Render functions.
-	tileCache map[uint32]image.Image
+	m *tiled.Map
+	// The image result after rendering using the Render functions. It is allocated with the size of the
+	// whole map image when first rendering to it, so that renderers only used to render regions never
+	// allocate it. Use ResultImage to get the result image even if nothing was rendered yet.
+	Result *image.NRGBA
+	// Style of rectangle, ellipse, polygon, polyline and point objects. Defaults to DefaultShapeStyle.
+	ShapeStyle ShapeStyle
+	// Resolver of fonts used to render text objects. Defaults to GoFontResolver.
+	FontResolver FontResolver
+	// Scale factor of the rendered image. Zero renders the map at its original size.
+	Scale float64
+	// Filter used to resample images when the rendered image is scaled, for example imaging.NearestNeighbor
+	// for pixel art or imaging.Lanczos for previews. Defaults to nearest-neighbor resampling.
+	ScaleFilter imaging.ResampleFilter
+	// Whether images of tiles and objects are scaled as they are drawn instead of scaling the composite
+	// image of each layer. This uses less memory, but images are resampled without their surroundings.
+	ScaleTiles bool
+	// Cache of tile images, which can be shared by renderers. Defaults to a new cache of the renderer.
+	TileCache *TileCache
 	engine    RendererEngine
 	fs        fs.FS
+	// Position in the image of the map pixel origin relative to the one assumed by the engine
+	origin image.Point
+	// Position of the camera used to move layers with parallax factors, in map pixels
+	cameraX, cameraY float64
+	// Time of tile animations
+	animationTime time.Duration
+	// Animation frames of tiles
+	animations *animationCache
 }
 
+// newNRGBA allocates images rendered to, tests replace it to check the sizes of the allocated images.
+var newNRGBA = image.NewNRGBA
+
 // NewRenderer creates new rendering engine instance.
 func NewRenderer(m *tiled.Map) (*Renderer, error) {
 	return NewRendererWithFileSystem(m, nil)
@@ -72,19 +110,44 @@ func NewRenderer(m *tiled.Map) (*Renderer, error) {
 
 // NewRendererWithFileSystem creates new rendering engine instance with a custom file system.
 func NewRendererWithFileSystem(m *tiled.Map, fs fs.FS) (*Renderer, error) {
-	r := &Renderer{m: m, tileCache: make(map[uint32]image.Image), fs: fs}
-	if r.m.Orientation == "orthogonal" {
+	r := &Renderer{
+		m:            m,
+		ShapeStyle:   DefaultShapeStyle,
+		FontResolver: &GoFontResolver{},
+		TileCache:    NewTileCache(),
+		animations:   &animationCache{frames: make(map[uint32][]*tiled.AnimationFrame)},
+		fs:           fs,
+	}
+	switch r.m.Orientation {
+	case "orthogonal":
 		r.engine = &OrthogonalRendererEngine{}
-	} else {
+	case "isometric":
+		r.engine = &IsometricRendererEngine{}
+	case "staggered":
+		r.engine = &StaggeredRendererEngine{}
+	case "hexagonal":
+		r.engine = &HexagonalRendererEngine{}
+	default:
 		return nil, ErrUnsupportedOrientation
 	}
 
 	r.engine.Init(r.m)
-	r.Clear()
+	o := r.gridOrigin()
+	r.origin = r.engine.GetTilePosition(-o.X, -o.Y).Min.Sub(r.engine.GetTilePosition(0, 0).Min)
+	r.SetCamera(m.ParallaxOriginX, m.ParallaxOriginY)
 
 	return r, nil
 }
 
+// gridOrigin returns map tile coordinates of the first tile of the layer tiles. Infinite maps
+// can contain chunks at negative coordinates, so their tiles start at the top-left of the map border.
+func (r *Renderer) gridOrigin() image.Point {
+	if !r.m.IsInfinite || r.m.Border == nil {
+		return image.Point{}
+	}
+	return image.Pt(r.m.Border.MinX, r.m.Border.MinY)
+}
+
 func (r *Renderer) open(f string) (io.ReadCloser, error) {
 	if r.fs != nil {
 		return r.fs.Open(f)
@@ -93,94 +156,287 @@ func (r *Renderer) open(f string) (io.ReadCloser, error) {
 }
 
 func (r *Renderer) getTileImage(tile *tiled.LayerTile) (image.Image, error) {
-	timg, ok := r.tileCache[tile.Tileset.FirstGID+tile.ID]
-	if ok {
-		return r.engine.RotateTileImage(tile, timg), nil
-	}
-	// Precache all tiles in tileset
-	if tile.Tileset.Image == nil {
-		for i := 0; i < len(tile.Tileset.Tiles); i++ {
-			if tile.Tileset.Tiles[i].ID == tile.ID {
-				sf, err := r.open(tile.Tileset.GetFileFullPath(tile.Tileset.Tiles[i].Image.Source))
-				if err != nil {
-					return nil, err
-				}
-				defer sf.Close()
-				timg, _, err = image.Decode(sf)
-				if err != nil {
-					return nil, err
-				}
-				r.tileCache[tile.Tileset.FirstGID+tile.ID] = timg
-			}
-		}
-	} else {
-		sf, err := r.open(tile.Tileset.GetFileFullPath(tile.Tileset.Image.Source))
-		if err != nil {
-			return nil, err
+	// Animated tiles are drawn using the tile of the animation frame at the current time
+	id := r.animatedTileID(tile)
+
+	timg, err := r.TileCache.tileImage(tile.Tileset, id, r.loadTileImages)
+	if err != nil {
+		return nil, err
+	}
+	return r.engine.RotateTileImage(tile, timg), nil
+}
+
+// pixelToScreenCoords converts object pixel coordinates to coordinates in the map image.
+func (r *Renderer) pixelToScreenCoords(x, y float64) (float64, float64) {
+	if e, ok := r.engine.(ScreenCoordsEngine); ok {
+		return e.PixelToScreenCoords(x, y)
+	}
+	return x, y
+}
+
+// rotatingEngine is implemented by engines that rotate tile images by angles other than multiples
+// of 90 degrees. Such images are larger than the tile and are centered on the unrotated tile image.
+type rotatingEngine interface {
+	tileRotation(tile *tiled.LayerTile) float64
+}
+
+// layerTileRect returns the rectangle the transformed tile image is drawn to.
+func (r *Renderer) layerTileRect(tile *tiled.LayerTile, img image.Image, cell image.Rectangle) (image.Rectangle, error) {
+	e, ok := r.engine.(rotatingEngine)
+	if !ok || e.tileRotation(tile) == 0 {
+		return tileImageRect(tile, img, cell), nil
+	}
+
+	src, err := r.TileCache.tileImage(tile.Tileset, r.animatedTileID(tile), r.loadTileImages)
+	if err != nil {
+		return image.Rectangle{}, err
+	}
+	frame := tileImageRect(tile, src, cell)
+
+	// The rotated image is scaled the same way as the tile image when it is rendered at the grid size
+	size := img.Bounds().Size()
+	srcSize := src.Bounds().Size()
+	if srcSize.X == 0 || srcSize.Y == 0 {
+		return frame, nil
+	}
+	size = image.Pt(size.X*frame.Dx()/srcSize.X, size.Y*frame.Dy()/srcSize.Y)
+	min := frame.Min.Add(frame.Size().Sub(size).Div(2))
+	return image.Rectangle{Min: min, Max: min.Add(size)}, nil
+}
+
+// loadTileImages loads the image of the tile. For tilesets based on a single image, images of all
+// tiles in the tileset are loaded. For collections of images, images of all tiles using the same
+// image file are loaded, cropped to their sub-rectangles.
+func (r *Renderer) loadTileImages(ts *tiled.Tileset, id uint32) (map[uint32]image.Image, error) {
+	images := make(map[uint32]image.Image)
+	if ts.Image == nil {
+		tile, err := ts.GetTilesetTile(id)
+		if err != nil || tile.Image == nil {
+			return nil, tiled.ErrInvalidTileGID
 		}
-		defer sf.Close()
 
-		img, _, err := image.Decode(sf)
+		timg, err := r.LoadTilesetImage(ts, tile.Image)
 		if err != nil {
 			return nil, err
 		}
 
-		for i := uint32(0); i < uint32(tile.Tileset.TileCount); i++ {
-			rect := tile.Tileset.GetTileRect(i)
-			r.tileCache[i+tile.Tileset.FirstGID] = imaging.Crop(img, rect)
-			if tile.ID == i {
-				timg = r.tileCache[i+tile.Tileset.FirstGID]
+		for _, t := range ts.Tiles {
+			if t.Image == nil || !sameImage(t.Image, tile.Image) {
+				continue
+			}
+			if t.Width > 0 && t.Height > 0 {
+				images[t.ID] = imaging.Crop(timg, ts.GetTileRect(t.ID))
+			} else {
+				images[t.ID] = timg
 			}
 		}
+		return images, nil
 	}
 
-	return r.engine.RotateTileImage(tile, timg), nil
+	img, err := r.LoadTilesetImage(ts, ts.Image)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := uint32(0); i < uint32(ts.TileCount); i++ {
+		images[i] = imaging.Crop(img, ts.GetTileRect(i))
+	}
+	return images, nil
 }
 
-func (r *Renderer) _renderLayer(layer *tiled.Layer) error {
+// LoadTilesetImage loads the image of the tileset, or of a tile in a collection of images. The image file
+// is resolved relative to the tileset and pixels of the transparent color of the image are made transparent.
+func (r *Renderer) LoadTilesetImage(ts *tiled.Tileset, img *tiled.Image) (image.Image, error) {
+	return r.loadImage(img, ts.GetFileFullPath(img.Source))
+}
 
-	var xs, xe, xi, ys, ye, yi int
-	if r.m.RenderOrder == "" || r.m.RenderOrder == "right-down" {
-		xs = 0
-		xe = r.m.Width
-		xi = 1
-		ys = 0
-		ye = r.m.Height
-		yi = 1
-	} else {
-		return ErrUnsupportedRenderOrder
+// sameImage reports whether both images are loaded from the same file, or are the same embedded image.
+func sameImage(a, b *tiled.Image) bool {
+	if a.IsEmbedded() || b.IsEmbedded() {
+		return a == b
 	}
+	return a.Source == b.Source
+}
 
-	i := 0
-	for y := ys; y*yi < ye; y = y + yi {
-		for x := xs; x*xi < xe; x = x + xi {
-			if layer.Tiles[i].IsNil() {
-				i++
-				continue
-			}
+// staggeredEngine is implemented by rendering engines of maps that are staggered along an axis.
+type staggeredEngine interface {
+	StaggerX() bool
+	IsStaggered(index int) bool
+}
 
-			img, err := r.getTileImage(layer.Tiles[i])
-			if err != nil {
-				return err
-			}
+// tileOrder returns coordinates of the map tiles in the order they are drawn. The map
+// is drawn row by row, in the direction specified by the map render order.
+func (r *Renderer) tileOrder() ([]image.Point, error) {
+	return r.tileOrderIn(image.Rect(0, 0, r.m.Width, r.m.Height))
+}
+
+// tileOrderIn returns coordinates of the map tiles within the area in the order they are drawn.
+func (r *Renderer) tileOrderIn(area image.Rectangle) ([]image.Point, error) {
+	var leftward, upward bool
+	switch r.m.RenderOrder {
+	case "", "right-down":
+	case "right-up":
+		upward = true
+	case "left-down":
+		leftward = true
+	case "left-up":
+		leftward, upward = true, true
+	default:
+		return nil, ErrUnsupportedRenderOrder
+	}
 
-			pos := r.engine.GetTilePosition(x, y)
+	// As in Tiled, render order is only applied to orthogonal maps. Other orientations
+	// are always drawn from top to bottom to keep tiles overlapping correctly.
+	if r.m.Orientation != "orthogonal" {
+		leftward, upward = false, false
+	}
 
-			if layer.Opacity < 1 {
-				mask := image.NewUniform(color.Alpha{uint8(layer.Opacity * 255)})
+	// For maps staggered along the X axis shifted columns are lower than the other
+	// columns of the same row, so they are drawn after them.
+	se, staggerX := r.engine.(staggeredEngine)
+	staggerX = staggerX && se.StaggerX()
 
-				draw.DrawMask(r.Result, pos, img, img.Bounds().Min, mask, mask.Bounds().Min, draw.Over)
-			} else {
-				draw.Draw(r.Result, pos, img, img.Bounds().Min, draw.Over)
+	columns := make([]int, area.Dx())
+	for i := range columns {
+		columns[i] = area.Min.X + i
+		if leftward {
+			columns[i] = area.Max.X - 1 - i
+		}
+	}
+
+	order := make([]image.Point, 0, area.Dx()*area.Dy())
+	for i := 0; i < area.Dy(); i++ {
+		y := area.Min.Y + i
+		if upward {
+			y = area.Max.Y - 1 - i
+		}
+		if !staggerX {
+			for _, x := range columns {
+				order = append(order, image.Pt(x, y))
+			}
+			continue
+		}
+		for _, staggered := range []bool{false, true} {
+			for _, x := range columns {
+				if se.IsStaggered(x) == staggered {
+					order = append(order, image.Pt(x, y))
+				}
 			}
+		}
+	}
+	return order, nil
+}
+
+func (r *Renderer) _renderLayer(layer *tiled.Layer, state *tiled.LayerState) error {
+	return r.composite(func() error {
+		return r.drawLayerTiles(layer, state)
+	})
+}
+
+func (r *Renderer) drawLayerTiles(layer *tiled.Layer, state *tiled.LayerState) error {
+	offset := r.layerOffset(state)
+
+	// Only tiles that can intersect the result image are drawn
+	order, err := r.tileOrderIn(r.tileArea(r.resultBounds().Sub(offset)))
+	if err != nil {
+		return err
+	}
+
+	for _, p := range order {
+		tile := layer.Tiles[p.Y*r.m.Width+p.X]
+		if tile.IsNil() {
+			continue
+		}
+
+		img, err := r.getTileImage(tile)
+		if err != nil {
+			return err
+		}
 
-			i++
+		pos, err := r.layerTileRect(tile, img, r.engine.GetTilePosition(p.X, p.Y))
+		if err != nil {
+			return err
 		}
+		r.drawImage(pos.Add(offset), img, state)
 	}
 
 	return nil
 }
 
+// tileArea returns the area of the map grid with all tiles which images can intersect the rectangle.
+func (r *Renderer) tileArea(rect image.Rectangle) image.Rectangle {
+	grid := image.Rect(0, 0, r.m.Width, r.m.Height)
+
+	// Tile images can be larger than the grid cells and moved by the tileset tile offset
+	margin := utils.Max(r.m.TileWidth, r.m.TileHeight)
+	for _, ts := range r.m.Tilesets {
+		extent := utils.Max(ts.TileWidth, ts.TileHeight)
+		// Tiles of collections of images can be larger than the tileset tile size
+		for _, t := range ts.Tiles {
+			switch {
+			case t.Width > 0 && t.Height > 0:
+				extent = utils.Max(extent, utils.Max(t.Width, t.Height))
+			case ts.Image == nil && t.Image != nil:
+				extent = utils.Max(extent, utils.Max(t.Image.Width, t.Image.Height))
+			}
+		}
+		if ts.TileOffset != nil {
+			extent += utils.Max(utils.Abs(ts.TileOffset.X), utils.Abs(ts.TileOffset.Y))
+		}
+		margin = utils.Max(margin, extent)
+	}
+	rect = rect.Inset(-margin)
+
+	// The grid is approximated by the average steps between cells, staggered cells
+	// are shifted by less than a cell, which is covered by the margin as well
+	o := r.engine.GetTilePosition(0, 0).Min
+	stepX := r.engine.GetTilePosition(2, 0).Min.Sub(o)
+	stepY := r.engine.GetTilePosition(0, 2).Min.Sub(o)
+	det := float64(stepX.X*stepY.Y-stepX.Y*stepY.X) / 4
+	if det == 0 {
+		return grid
+	}
+
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, p := range []image.Point{rect.Min, rect.Max, image.Pt(rect.Min.X, rect.Max.Y), image.Pt(rect.Max.X, rect.Min.Y)} {
+		px, py := float64(p.X-o.X), float64(p.Y-o.Y)
+		x := (px*float64(stepY.Y) - py*float64(stepY.X)) / 2 / det
+		y := (py*float64(stepX.X) - px*float64(stepX.Y)) / 2 / det
+		minX, minY = math.Min(minX, x), math.Min(minY, y)
+		maxX, maxY = math.Max(maxX, x), math.Max(maxY, y)
+	}
+
+	area := image.Rect(int(math.Floor(minX))-1, int(math.Floor(minY))-1, int(math.Ceil(maxX))+1, int(math.Ceil(maxY))+1)
+	return area.Intersect(grid)
+}
+
+// fillSize returns the size an image is drawn at to fill the target size with the fill mode.
+func fillSize(size, target image.Point, mode tiled.FillMode) image.Point {
+	if mode != tiled.FillModePreserveAspectFit || size.X == 0 || size.Y == 0 {
+		return target
+	}
+	scale := math.Min(float64(target.X)/float64(size.X), float64(target.Y)/float64(size.Y))
+	return image.Pt(int(math.Round(float64(size.X)*scale)), int(math.Round(float64(size.Y)*scale)))
+}
+
+// tileImageRect returns the rectangle the tile image is drawn to. As in Tiled, images are
+// aligned to the bottom-left corner of the tile cell, so that tiles larger than the map grid
+// extend upwards and to the right, and moved by the tile offset of the tileset. Tilesets rendered
+// at the grid size fill the cell, or are centered in it when their aspect ratio is preserved.
+func tileImageRect(tile *tiled.LayerTile, img image.Image, cell image.Rectangle) image.Rectangle {
+	size := img.Bounds().Size()
+	min := image.Pt(cell.Min.X, cell.Max.Y-size.Y)
+	if tile.Tileset.TileRenderSize == tiled.TileRenderSizeGrid {
+		size = fillSize(size, cell.Size(), tile.Tileset.FillMode)
+		min = cell.Min.Add(cell.Size().Sub(size).Div(2))
+	}
+	if offset := tile.Tileset.TileOffset; offset != nil {
+		min = min.Add(image.Pt(offset.X, offset.Y))
+	}
+	return image.Rectangle{Min: min, Max: min.Add(size)}
+}
+
 // RenderGroupLayer renders single map layer in a certain group.
 func (r *Renderer) RenderGroupLayer(groupID, layerID int) error {
 	if groupID >= len(r.m.Groups) {
@@ -191,7 +447,8 @@ func (r *Renderer) RenderGroupLayer(groupID, layerID int) error {
 	if layerID >= len(group.Layers) {
 		return ErrOutOfBounds
 	}
-	return r._renderLayer(group.Layers[layerID])
+	layer := group.Layers[layerID]
+	return r._renderLayer(layer, r.layerState(layer))
 }
 
 // RenderLayer renders single map layer.
@@ -199,7 +456,124 @@ func (r *Renderer) RenderLayer(id int) error {
 	if id >= len(r.m.Layers) {
 		return ErrOutOfBounds
 	}
-	return r._renderLayer(r.m.Layers[id])
+	layer := r.m.Layers[id]
+	return r._renderLayer(layer, r.layerState(layer))
+}
+
+// LayerBounds returns the rectangle of the rendered map image that is covered by the non-empty
+// tiles of the layer. It is empty if the layer has no tiles.
+func (r *Renderer) LayerBounds(layer *tiled.Layer) image.Rectangle {
+	b := layer.Border
+	if b == nil {
+		return image.Rectangle{}
+	}
+
+	state := r.layerState(layer)
+	bounds := r.tileRegion(image.Rect(b.MinX, b.MinY, b.MaxX+1, b.MaxY+1))
+	return r.scaleRect(bounds.Add(r.layerOffset(state)))
+}
+
+// RenderLayerParallel renders single tile layer, which can also be nested in a group, using the given
+// number of goroutines, or one per CPU if it is not positive. The result image is split into horizontal
+// bands, which are rendered independently. Offsets of the parent groups of the layer are applied.
+func (r *Renderer) RenderLayerParallel(layer *tiled.Layer, workers int) error {
+	r.ensureResult()
+	state := r.layerState(layer)
+
+	bounds := r.Result.Bounds()
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > bounds.Dy() {
+		workers = bounds.Dy()
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, workers)
+	for i := 0; i < workers; i++ {
+		// Renderers of the bands share everything but the result, tiles crossing the
+		// band edges are drawn to each of them and clipped
+		band := *r
+		minY := bounds.Min.Y + bounds.Dy()*i/workers
+		maxY := bounds.Min.Y + bounds.Dy()*(i+1)/workers
+		band.Result = r.Result.SubImage(image.Rect(bounds.Min.X, minY, bounds.Max.X, maxY)).(*image.NRGBA)
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = band._renderLayer(layer, state)
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TileRegion returns the rectangle of the rendered map image that is covered by the cells of
+// the tiles within the rectangle given in map tile coordinates. The result can be passed to
+// RenderRegion to render a viewport given in tiles.
+func (r *Renderer) TileRegion(tiles image.Rectangle) image.Rectangle {
+	return r.scaleRect(r.tileRegion(tiles))
+}
+
+// tileRegion returns the rectangle of the map image at its original size covered by the tile cells.
+func (r *Renderer) tileRegion(tiles image.Rectangle) image.Rectangle {
+	var bounds image.Rectangle
+	if tiles.Empty() {
+		return bounds
+	}
+
+	// Extremes of the cells are always on the edges of the rectangle
+	o := r.gridOrigin()
+	for y := tiles.Min.Y; y < tiles.Max.Y; y++ {
+		step := 1
+		if y != tiles.Min.Y && y != tiles.Max.Y-1 && tiles.Dx() > 1 {
+			step = tiles.Dx() - 1
+		}
+		for x := tiles.Min.X; x < tiles.Max.X; x += step {
+			bounds = bounds.Union(r.engine.GetTilePosition(x-o.X, y-o.Y))
+		}
+	}
+	return bounds
+}
+
+// RenderRegion renders all visible layers of the map within the region, given in pixels of the
+// whole map image, to dst. The top-left corner of the region is drawn at the top-left corner of
+// the dst bounds, over its existing content. Only tiles and objects that intersect the region are
+// rendered, so the whole map image is never allocated. Regions can extend beyond the map image, for
+// example to show parts of tiles larger than the map grid. The render result is left unchanged.
+func (r *Renderer) RenderRegion(dst draw.Image, region image.Rectangle) error {
+	result := r.Result
+	defer func() { r.Result = result }()
+
+	// NRGBA images are rendered to directly, using a view of their pixels with coordinates of the map image
+	if img, ok := dst.(*image.NRGBA); ok {
+		view := *img
+		view.Rect = img.Rect.Add(region.Min.Sub(img.Rect.Min))
+		r.Result = view.SubImage(region).(*image.NRGBA)
+		return r.RenderVisibleMapLayers()
+	}
+
+	r.Result = newNRGBA(region)
+	if err := r.RenderVisibleMapLayers(); err != nil {
+		return err
+	}
+
+	draw.Draw(dst, dst.Bounds(), r.Result, region.Min, draw.Over)
+	return nil
+}
+
+// RenderLayerBounds renders single tile layer, which can also be nested in a group, to a new
+// result image that only covers the non-empty tiles of the layer. Bounds of the result image
+// are the same as returned by LayerBounds, so they are relative to the whole map image.
+func (r *Renderer) RenderLayerBounds(layer *tiled.Layer) error {
+	r.Result = newNRGBA(r.LayerBounds(layer))
+	return r._renderLayer(layer, r.layerState(layer))
 }
 
 // RenderVisibleLayers renders all visible map layers.
@@ -217,24 +591,153 @@ func (r *Renderer) RenderVisibleLayers() error {
 	return nil
 }
 
+// RenderVisibleMapLayers renders all visible layers of all kinds, including groups,
+// in the order they appear in the map.
+func (r *Renderer) RenderVisibleMapLayers() error {
+	return r.m.WalkLayers(r.renderVisibleLayer)
+}
+
+// RenderMapLayer renders single layer of any kind, even if it is hidden. For groups all visible
+// child layers are rendered. Offsets of the parent groups of the layer are applied.
+func (r *Renderer) RenderMapLayer(layer tiled.MapLayer) error {
+	state := r.layerState(layer)
+	group, ok := layer.(*tiled.Group)
+	if !ok {
+		return r.renderLayerContent(layer, state)
+	}
+
+	return group.WalkLayers(func(l tiled.MapLayer, relative *tiled.LayerState) error {
+		if !relative.Visible {
+			return tiled.SkipGroup
+		}
+		return r.renderLayerContent(l, nestedLayerState(group, state, relative))
+	})
+}
+
+// renderVisibleLayer is a WalkLayersFunc that renders visible layers and skips hidden groups.
+func (r *Renderer) renderVisibleLayer(layer tiled.MapLayer, state *tiled.LayerState) error {
+	if !state.Visible {
+		return tiled.SkipGroup
+	}
+	return r.renderLayerContent(layer, state)
+}
+
+// renderLayerContent renders tile, object and image layers. Layers of groups are rendered separately.
+func (r *Renderer) renderLayerContent(layer tiled.MapLayer, state *tiled.LayerState) error {
+	switch l := layer.(type) {
+	case *tiled.Layer:
+		return r._renderLayer(l, state)
+	case *tiled.ObjectGroup:
+		return r._renderObjectGroup(l, state)
+	case *tiled.ImageLayer:
+		return r._renderImageLayer(l, state)
+	}
+	return nil
+}
+
+// errLayerFound stops walking the map layers once the layer is found.
+var errLayerFound = errors.New("tiled/render: layer found")
+
+// layerState returns the effective state of the layer in the map. Layers that are not part
+// of the map only have their own state.
+func (r *Renderer) layerState(layer tiled.MapLayer) *tiled.LayerState {
+	var found *tiled.LayerState
+	_ = r.m.WalkLayers(func(l tiled.MapLayer, state *tiled.LayerState) error {
+		if l == layer {
+			found = state
+			return errLayerFound
+		}
+		return nil
+	})
+	if found != nil {
+		return found
+	}
+
+	offsetX, offsetY := layer.GetOffset()
+	parallaxX, parallaxY := layer.GetParallax()
+	return &tiled.LayerState{
+		Opacity:   layer.GetOpacity(),
+		Visible:   layer.IsVisible(),
+		OffsetX:   offsetX,
+		OffsetY:   offsetY,
+		ParallaxX: parallaxX,
+		ParallaxY: parallaxY,
+		TintColor: layer.GetTintColor(),
+	}
+}
+
+// nestedLayerState combines the state of the group with the state of a layer relative to it.
+func nestedLayerState(group *tiled.Group, state, relative *tiled.LayerState) *tiled.LayerState {
+	parents := make([]*tiled.Group, 0, len(state.Parents)+1+len(relative.Parents))
+	parents = append(append(append(parents, state.Parents...), group), relative.Parents...)
+	return &tiled.LayerState{
+		Parents:   parents,
+		Opacity:   state.Opacity * relative.Opacity,
+		Visible:   state.Visible && relative.Visible,
+		OffsetX:   state.OffsetX + relative.OffsetX,
+		OffsetY:   state.OffsetY + relative.OffsetY,
+		ParallaxX: state.ParallaxX * relative.ParallaxX,
+		ParallaxY: state.ParallaxY * relative.ParallaxY,
+		TintColor: tiled.MultiplyHexColors(state.TintColor, relative.TintColor),
+	}
+}
+
+// SetCamera sets the position of the camera, which is the center of the view in map pixels. Layers
+// with parallax factors other than 1 are moved relative to it, the same way as Tiled does when the view
+// is centered at the position. The camera is initially placed at the parallax origin of the map, where
+// layers are not moved.
+func (r *Renderer) SetCamera(x, y float64) {
+	r.cameraX, r.cameraY = x, y
+}
+
+// layerOffset returns the offset of a layer with the given state, including the parallax offset.
+func (r *Renderer) layerOffset(state *tiled.LayerState) image.Point {
+	parallaxX := (r.cameraX - r.m.ParallaxOriginX) * (1 - state.ParallaxX)
+	parallaxY := (r.cameraY - r.m.ParallaxOriginY) * (1 - state.ParallaxY)
+	return image.Pt(state.OffsetX+int(math.Round(parallaxX)), state.OffsetY+int(math.Round(parallaxY)))
+}
+
+// layerOrigin returns the position in the result image of the map pixel origin for a layer with the given state.
+func (r *Renderer) layerOrigin(state *tiled.LayerState) image.Point {
+	return r.origin.Add(r.layerOffset(state))
+}
+
 // Clear clears the render result to allow for separation of layers. For example, you can
 // render a layer, make a copy of the render, clear the renderer, and repeat for each
-// layer in the Map.
+// layer in the Map. A new result image is allocated when rendering to it again, with the
+// size of the map image at the scale of that time.
 func (r *Renderer) Clear() {
-	r.Result = image.NewNRGBA(r.engine.GetFinalImageSize())
+	r.Result = nil
+}
+
+// ResultImage returns the render result. An empty image of the whole map is allocated if nothing
+// was rendered yet.
+func (r *Renderer) ResultImage() *image.NRGBA {
+	r.ensureResult()
+	return r.Result
+}
+
+// ensureResult allocates the result image of the whole map if there is none.
+func (r *Renderer) ensureResult() {
+	if r.Result == nil {
+		r.Result = newNRGBA(r.imageBounds())
+	}
 }
 
 // SaveAsPng writes rendered layers as PNG image to provided writer.
 func (r *Renderer) SaveAsPng(w io.Writer) error {
+	r.ensureResult()
 	return png.Encode(w, r.Result)
 }
 
 // SaveAsJpeg writes rendered layers as JPEG image to provided writer.
 func (r *Renderer) SaveAsJpeg(w io.Writer, options *jpeg.Options) error {
+	r.ensureResult()
 	return jpeg.Encode(w, r.Result, options)
 }
 
 // SaveAsGif writes rendered layers as GIF image to provided writer.
 func (r *Renderer) SaveAsGif(w io.Writer, options *gif.Options) error {
+	r.ensureResult()
 	return gif.Encode(w, r.Result, options)
 }
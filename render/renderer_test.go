@@ -0,0 +1,454 @@
+/*
+Copyright (c) 2023 Lauris Bukšis-Haberkorns <lauris@nix.lv>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+
+	"github.com/disintegration/imaging"
+	"github.com/lafriks/go-tiled"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderVisibleMapLayers(t *testing.T) {
+	m, err := tiled.LoadFile("../assets/test_layer_order.tmx")
+	assert.NoError(t, err)
+
+	renderer, err := NewRenderer(m)
+	assert.NoError(t, err)
+	assert.NoError(t, renderer.RenderVisibleMapLayers())
+
+	// Layers must be drawn in the document order: tile layer, object group, tile layer
+	expected, err := NewRenderer(m)
+	assert.NoError(t, err)
+	assert.NoError(t, expected.RenderLayer(0))
+	assert.NoError(t, expected.RenderObjectGroup(0))
+	assert.NoError(t, expected.RenderLayer(1))
+
+	assert.Equal(t, expected.Result, renderer.Result)
+
+	layersAndObjects, err := NewRenderer(m)
+	assert.NoError(t, err)
+	assert.NoError(t, layersAndObjects.RenderVisibleLayersAndObjectGroups())
+	assert.Equal(t, expected.Result, layersAndObjects.Result)
+
+	// Layers of maps built in code are rendered ordered by their kind
+	m.Children = nil
+	expected, err = NewRenderer(m)
+	assert.NoError(t, err)
+	assert.NoError(t, expected.RenderLayer(0))
+	assert.NoError(t, expected.RenderLayer(1))
+	assert.NoError(t, expected.RenderObjectGroup(0))
+
+	layersAndObjects, err = NewRenderer(m)
+	assert.NoError(t, err)
+	assert.NoError(t, layersAndObjects.RenderVisibleLayersAndObjectGroups())
+	assert.Equal(t, expected.Result, layersAndObjects.Result)
+	assert.NotEqual(t, image.NewNRGBA(expected.Result.Bounds()).Pix, layersAndObjects.Result.Pix)
+}
+
+func TestRenderOrder(t *testing.T) {
+	tests := []struct {
+		renderOrder string
+		order       []image.Point
+	}{
+		{"right-down", []image.Point{{0, 0}, {1, 0}, {0, 1}, {1, 1}}},
+		{"right-up", []image.Point{{0, 1}, {1, 1}, {0, 0}, {1, 0}}},
+		{"left-down", []image.Point{{1, 0}, {0, 0}, {1, 1}, {0, 1}}},
+		{"left-up", []image.Point{{1, 1}, {0, 1}, {1, 0}, {0, 0}}},
+	}
+
+	results := map[string][]uint8{}
+	for _, tt := range tests {
+		t.Run(tt.renderOrder, func(t *testing.T) {
+			m, err := tiled.LoadFile("../assets/test_render_order.tmx")
+			assert.NoError(t, err)
+			m.RenderOrder = tt.renderOrder
+
+			r, err := NewRenderer(m)
+			assert.NoError(t, err)
+
+			order, err := r.tileOrder()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.order, order)
+
+			// Tiles are larger than the map grid, so they overlap depending on the order
+			assert.NoError(t, r.RenderLayer(0))
+			expected := image.NewNRGBA(r.Result.Bounds())
+			for _, p := range tt.order {
+				tile := m.Layers[0].Tiles[p.Y*m.Width+p.X]
+				img, err := r.getTileImage(tile)
+				assert.NoError(t, err)
+				cell := r.engine.GetTilePosition(p.X, p.Y)
+				min := image.Pt(cell.Min.X, cell.Max.Y-img.Bounds().Dy())
+				draw.Draw(expected, image.Rectangle{Min: min, Max: min.Add(img.Bounds().Size())}, img, img.Bounds().Min, draw.Over)
+			}
+			assert.Equal(t, expected.Pix, r.Result.Pix)
+			results[tt.renderOrder] = r.Result.Pix
+		})
+	}
+	assert.NotEqual(t, results["right-down"], results["left-up"])
+	assert.NotEqual(t, results["right-up"], results["left-down"])
+
+	m, err := tiled.LoadFile("../assets/test_render_order.tmx")
+	assert.NoError(t, err)
+	m.RenderOrder = "up-down"
+	r, err := NewRenderer(m)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, r.RenderLayer(0), ErrUnsupportedRenderOrder)
+}
+
+func TestRenderInfinite(t *testing.T) {
+	m, err := tiled.LoadFile("../assets/test_infinite_negative.tmx")
+	assert.NoError(t, err)
+
+	r, err := NewRenderer(m)
+	assert.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 32*32, 32*32), r.ResultImage().Bounds())
+
+	// Tile at (-1,-1) is drawn right before the map origin
+	layer := m.Layers[0]
+	expected := image.NewNRGBA(r.Result.Bounds())
+	for _, p := range []image.Point{{-1, -1}, {0, 0}, {2, 1}} {
+		tile, err := r.getTileImage(layer.Tiles[(p.Y+16)*32+p.X+16])
+		assert.NoError(t, err)
+		rect := image.Rect(p.X*32, p.Y*32, (p.X+1)*32, (p.Y+1)*32).Add(image.Pt(16*32, 16*32))
+		draw.Draw(expected, rect, tile, tile.Bounds().Min, draw.Over)
+	}
+	assert.NoError(t, r.RenderLayer(0))
+	assert.Equal(t, expected.Pix, r.Result.Pix)
+
+	// Object placed at the same tile covers it exactly
+	tile, err := r.getTileImage(layer.Tiles[15*32+15])
+	assert.NoError(t, err)
+	objects, err := NewRenderer(m)
+	assert.NoError(t, err)
+	assert.NoError(t, objects.RenderObjectGroup(0))
+	assertDrawn(t, objects, tile, image.Rect(15*32, 15*32, 16*32, 16*32))
+
+	bounds := image.Rect(15*32, 15*32, 19*32, 18*32)
+	assert.Equal(t, bounds, r.LayerBounds(layer))
+	assert.NoError(t, r.RenderLayerBounds(layer))
+	assert.Equal(t, bounds, r.Result.Bounds())
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if !assert.Equal(t, expected.NRGBAAt(x, y), r.Result.NRGBAAt(x, y)) {
+				return
+			}
+		}
+	}
+}
+
+func TestRenderOffsets(t *testing.T) {
+	m, err := tiled.LoadFile("../assets/test_render_offsets.tmx")
+	assert.NoError(t, err)
+
+	r, err := NewRenderer(m)
+	assert.NoError(t, err)
+	blue := color.NRGBA{0, 0, 255, 255}
+	r.ShapeStyle = ShapeStyle{FillColor: blue}
+	assert.NoError(t, r.RenderVisibleMapLayers())
+
+	tile, err := r.getTileImage(m.Layers[0].Tiles[2*4+1])
+	assert.NoError(t, err)
+	nested := m.Groups[0].Layers[0]
+	nestedTile, err := r.getTileImage(nested.Tiles[0])
+	assert.NoError(t, err)
+
+	// Tall tiles are aligned to the bottom-left corner of the cell and moved by the tileset
+	// tile offset and the offsets of the layer and its parent groups
+	tileRect := image.Rect(22, 21, 54, 53)
+	nestedRect := image.Rect(6, -13, 38, 19)
+	expected := image.NewNRGBA(r.Result.Bounds())
+	draw.Draw(expected, tileRect, tile, tile.Bounds().Min, draw.Over)
+	draw.Draw(expected, nestedRect, nestedTile, nestedTile.Bounds().Min, draw.Over)
+	draw.Draw(expected, image.Rect(43, 5, 51, 13), image.NewUniform(blue), image.Point{}, draw.Over)
+	assert.Equal(t, expected.Pix, r.Result.Pix)
+
+	// Offsets of parent groups are applied when rendering a single layer
+	r.Clear()
+	assert.NoError(t, r.RenderMapLayer(nested))
+	assertDrawn(t, r, nestedTile, nestedRect)
+
+	r.Clear()
+	assert.NoError(t, r.RenderGroupLayer(0, 0))
+	assertDrawn(t, r, nestedTile, nestedRect)
+
+	r.Clear()
+	assert.NoError(t, r.RenderLayer(0))
+	assertDrawn(t, r, tile, tileRect)
+}
+
+func TestRenderTintAndParallax(t *testing.T) {
+	m, err := tiled.LoadFile("../assets/test_render_tint.tmx")
+	assert.NoError(t, err)
+
+	r, err := NewRenderer(m)
+	assert.NoError(t, err)
+	r.ShapeStyle = ShapeStyle{FillColor: color.White}
+
+	far := m.Groups[0].Layers[0]
+	assert.NoError(t, r.RenderMapLayer(far))
+
+	tile, err := r.getTileImage(far.Tiles[1*4+1])
+	assert.NoError(t, err)
+
+	// Tile colors are multiplied by the tint colors of the layer and its group, and the opacity
+	// of the group is applied to the layer
+	tinted := image.NewNRGBA(tile.Bounds())
+	draw.Draw(tinted, tinted.Bounds(), tile, tile.Bounds().Min, draw.Src)
+	for i := 0; i < len(tinted.Pix); i += 4 {
+		tinted.Pix[i+1] = uint8((uint16(tinted.Pix[i+1])*128 + 127) / 255)
+		tinted.Pix[i+2] = uint8((uint16(tinted.Pix[i+2])*64 + 127) / 255)
+		tinted.Pix[i+3] = uint8((uint16(tinted.Pix[i+3])*128 + 127) / 255)
+	}
+	expected := image.NewNRGBA(r.Result.Bounds())
+	mask := image.NewUniform(color.Alpha{127})
+	draw.DrawMask(expected, image.Rect(16, 0, 48, 32), tinted, tinted.Bounds().Min, mask, image.Point{}, draw.Over)
+	assert.Equal(t, expected.Pix, r.Result.Pix)
+
+	r.Clear()
+	assert.NoError(t, r.RenderMapLayer(m.Groups[0].ObjectGroups[0]))
+	c := r.Result.NRGBAAt(44, 4)
+	assert.Equal(t, uint8(255), c.R)
+	assert.InDelta(t, 128, c.G, 2)
+	assert.InDelta(t, 64, c.B, 2)
+	assert.InDelta(t, 127, c.A, 1)
+
+	// Moving the camera away from the parallax origin moves layers by the distance multiplied by
+	// one minus their parallax factor
+	r.Clear()
+	assert.NoError(t, r.RenderMapLayer(far))
+	still := r.Result
+	r.SetCamera(8+40, 4+40)
+	r.Clear()
+	assert.NoError(t, r.RenderMapLayer(far))
+	for y := 0; y < 64-30; y++ {
+		for x := 0; x < 64-20; x++ {
+			if !assert.Equal(t, still.NRGBAAt(x, y), r.Result.NRGBAAt(x+20, y+30)) {
+				return
+			}
+		}
+	}
+
+	r.Clear()
+	assert.NoError(t, r.RenderMapLayer(m.Groups[0].ObjectGroups[0]))
+	assert.Equal(t, image.Rect(40, 0, 48, 8), inkBounds(r, r.Result.Bounds()))
+}
+
+func TestRenderTileSizeAndAlignment(t *testing.T) {
+	m, err := tiled.LoadFile("../assets/test_render_alignment.tmx")
+	assert.NoError(t, err)
+
+	r, err := NewRenderer(m)
+	assert.NoError(t, err)
+
+	tile, err := r.getTileImage(m.Layers[0].Tiles[1*4+1])
+	assert.NoError(t, err)
+	small := imaging.Resize(tile, 16, 16, imaging.NearestNeighbor)
+
+	// Tiles of a tileset rendered at the grid size are scaled down to the map cell
+	assert.NoError(t, r.RenderLayer(0))
+	assertDrawn(t, r, small, image.Rect(16, 16, 32, 32))
+
+	// The centered tile object keeps the aspect ratio of the tile inside the object box,
+	// the other one is aligned to the bottom-left corner
+	r.Clear()
+	assert.NoError(t, r.RenderObjectGroup(0))
+	expected := image.NewNRGBA(r.Result.Bounds())
+	draw.Draw(expected, image.Rect(24, 24, 40, 40), small, image.Point{}, draw.Over)
+	draw.Draw(expected, image.Rect(0, 48, 16, 64), small, image.Point{}, draw.Over)
+	assert.Equal(t, expected.Pix, r.Result.Pix)
+}
+
+func TestRenderCollectionSubRects(t *testing.T) {
+	m, err := tiled.LoadFile("../assets/test_render_collection.tmx")
+	assert.NoError(t, err)
+
+	r, err := NewRenderer(m)
+	assert.NoError(t, err)
+	assert.NoError(t, r.RenderLayer(0))
+
+	sheet, err := r.loadImage(&tiled.Image{}, "../assets/tilesets/RPG_Nature_Tileset.png")
+	assert.NoError(t, err)
+	small, err := r.loadImage(&tiled.Image{}, "../assets/test_image_layer.png")
+	assert.NoError(t, err)
+
+	// Tiles using sub-rectangles of the same image are aligned to the bottom-left corner of their cells
+	expected := image.NewNRGBA(r.Result.Bounds())
+	draw.Draw(expected, image.Rect(0, 0, 64, 32), sheet, image.Pt(0, 0), draw.Over)
+	draw.Draw(expected, image.Rect(16, 56, 24, 64), small, small.Bounds().Min, draw.Over)
+	draw.Draw(expected, image.Rect(48, 16, 80, 64), sheet, image.Pt(64, 32), draw.Over)
+	assert.Equal(t, expected.Pix, r.Result.Pix)
+
+	r.Clear()
+	assert.NoError(t, r.RenderObjectGroup(0))
+	expected = image.NewNRGBA(r.Result.Bounds())
+	draw.Draw(expected, image.Rect(0, 16, 32, 64), sheet, image.Pt(64, 32), draw.Over)
+	assert.Equal(t, expected.Pix, r.Result.Pix)
+
+	// Tiles missing from the collection are reported
+	_, err = r.getTileImage(&tiled.LayerTile{ID: 5, Tileset: m.Tilesets[0]})
+	assert.ErrorIs(t, err, tiled.ErrInvalidTileGID)
+}
+
+func TestRenderTilesetTransparentColor(t *testing.T) {
+	m, err := tiled.LoadFile("../assets/test_tileset_trans.tmx")
+	assert.NoError(t, err)
+
+	r, err := NewRenderer(m)
+	assert.NoError(t, err)
+
+	// Pixels of the transparent color are keyed out of tileset and collection images
+	for _, ts := range m.Tilesets {
+		img := ts.Image
+		if img == nil {
+			img = ts.Tiles[0].Image
+		}
+		keyed, err := r.LoadTilesetImage(ts, img)
+		if assert.NoError(t, err) {
+			_, _, _, a := keyed.At(0, 0).RGBA()
+			assert.Zero(t, a)
+			assert.Equal(t, color.NRGBA{255, 0, 0, 255}, color.NRGBAModel.Convert(keyed.At(4, 4)))
+		}
+	}
+
+	keyed, err := r.LoadTilesetImage(m.Tilesets[0], m.Tilesets[0].Image)
+	assert.NoError(t, err)
+
+	assert.NoError(t, r.RenderLayer(0))
+	expected := image.NewNRGBA(r.Result.Bounds())
+	draw.Draw(expected, image.Rect(0, 0, 8, 8), keyed, image.Point{}, draw.Over)
+	draw.Draw(expected, image.Rect(12, 8, 20, 16), keyed, image.Point{}, draw.Over)
+	assert.Equal(t, expected.Pix, r.Result.Pix)
+}
+
+func TestRenderRegion(t *testing.T) {
+	for _, name := range []string{
+		"test_render_offsets.tmx",
+		"test_render_objects.tmx",
+		"test_render_text.tmx",
+		"test_isometric.tmx",
+		"test_hexagonal.tmx",
+		"test_infinite_negative.tmx",
+	} {
+		m, err := tiled.LoadFile("../assets/" + name)
+		assert.NoError(t, err)
+
+		r, err := NewRenderer(m)
+		assert.NoError(t, err)
+		assert.NoError(t, r.RenderVisibleMapLayers())
+		full := r.Result
+
+		size := full.Bounds().Size()
+		for _, region := range []image.Rectangle{
+			full.Bounds(),
+			image.Rect(size.X/3, size.Y/4, size.X*2/3, size.Y*3/4),
+			image.Rect(0, 0, size.X/2, size.Y/2),
+			image.Rect(size.X/2, size.Y/3, size.X, size.Y),
+		} {
+			// The region is drawn over the existing content of the destination
+			dst := image.NewRGBA(image.Rect(10, 20, 10+region.Dx(), 20+region.Dy()))
+			draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+			assert.NoError(t, r.RenderRegion(dst, region))
+
+			expected := image.NewRGBA(dst.Bounds())
+			draw.Draw(expected, expected.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+			draw.Draw(expected, expected.Bounds(), full, region.Min, draw.Over)
+			assert.Equal(t, expected.Pix, dst.Pix, "%s region %v", name, region)
+		}
+		assert.Same(t, full, r.Result)
+	}
+}
+
+func TestRenderRegionTiles(t *testing.T) {
+	m, err := tiled.LoadFile("../assets/racing.tmx")
+	assert.NoError(t, err)
+
+	r, err := NewRenderer(m)
+	assert.NoError(t, err)
+
+	region := r.TileRegion(image.Rect(40, 30, 45, 33))
+	assert.Equal(t, image.Rect(40*128, 30*128, 45*128, 33*128), region)
+
+	// Only tiles close to the region are drawn
+	area := r.tileArea(region)
+	assert.True(t, area.Overlaps(image.Rect(40, 30, 45, 33)))
+	assert.LessOrEqual(t, area.Dx()*area.Dy(), 9*7)
+
+	result := r.Result
+	dst := image.NewNRGBA(image.Rect(0, 0, region.Dx(), region.Dy()))
+	assert.NoError(t, r.RenderRegion(dst, region))
+	assert.Same(t, result, r.Result)
+}
+
+// trackImages records bounds of images allocated to be rendered to until the end of the test.
+func trackImages(t *testing.T) *[]image.Rectangle {
+	var allocated []image.Rectangle
+	newNRGBA = func(r image.Rectangle) *image.NRGBA {
+		allocated = append(allocated, r)
+		return image.NewNRGBA(r)
+	}
+	t.Cleanup(func() { newNRGBA = image.NewNRGBA })
+	return &allocated
+}
+
+func TestRenderRegionAllocations(t *testing.T) {
+	m, err := tiled.LoadFile("../assets/racing.tmx")
+	assert.NoError(t, err)
+
+	allocated := trackImages(t)
+	r, err := NewRenderer(m)
+	assert.NoError(t, err)
+	assert.Nil(t, r.Result)
+
+	// Images of the region size are allocated only for destinations that are not rendered to directly
+	region := r.TileRegion(image.Rect(40, 30, 45, 33))
+	assert.NoError(t, r.RenderRegion(image.NewNRGBA(image.Rect(0, 0, region.Dx(), region.Dy())), region))
+	assert.Empty(t, *allocated)
+	assert.NoError(t, r.RenderRegion(image.NewRGBA(image.Rect(0, 0, region.Dx(), region.Dy())), region))
+	assert.Equal(t, []image.Rectangle{region}, *allocated)
+	assert.Nil(t, r.Result)
+}
+
+func TestRenderLayerParallel(t *testing.T) {
+	for _, name := range []string{"test_wangsets_map.tmx", "test_hexagonal.tmx", "test_render_offsets.tmx", "test_infinite_negative.tmx"} {
+		m, err := tiled.LoadFile("../assets/" + name)
+		assert.NoError(t, err)
+
+		for _, layer := range m.AllLayers {
+			r, err := NewRenderer(m)
+			assert.NoError(t, err)
+			assert.NoError(t, r.RenderMapLayer(layer))
+
+			parallel, err := NewRenderer(m)
+			assert.NoError(t, err)
+			parallel.TileCache = r.TileCache
+			assert.NoError(t, parallel.RenderLayerParallel(layer, 7))
+			assert.Equal(t, r.Result.Pix, parallel.Result.Pix, "%s layer %s", name, layer.Name)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+/*
+Copyright (c) 2023 Lauris Bukšis-Haberkorns <lauris@nix.lv>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package render
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"image"
+	"path/filepath"
+	"sync"
+
+	"github.com/lafriks/go-tiled"
+)
+
+// TileCache is a cache of decoded tile images that is safe for concurrent use, so that it can be
+// shared by renderers, even of different maps, to decode each tileset image only once. Images are
+// keyed by the tileset source, the tile ID and the sub-rectangle of collection tiles, so renderers
+// sharing a cache must read tilesets from the same file system. Images of image layers are cached
+// as well. Images are kept until the cache is cleared.
+type TileCache struct {
+	mu     sync.Mutex
+	images map[tileCacheKey]image.Image
+	// Locks held while loading images of a tileset source, so that they are loaded only once
+	loading map[string]*sync.Mutex
+}
+
+type tileCacheKey struct {
+	source string
+	id     uint32
+	rect   image.Rectangle
+}
+
+// NewTileCache creates new empty tile image cache.
+func NewTileCache() *TileCache {
+	return &TileCache{
+		images:  make(map[tileCacheKey]image.Image),
+		loading: make(map[string]*sync.Mutex),
+	}
+}
+
+// Clear removes all images from the cache, for example when maps using them are no longer rendered.
+// Images that are being loaded while clearing the cache can still be added to it.
+func (c *TileCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.images = make(map[tileCacheKey]image.Image)
+	c.loading = make(map[string]*sync.Mutex)
+}
+
+// tileCacheSource returns the file identifying the tile in the cache. It is the file of external
+// tilesets, while tilesets embedded in a map are identified by their image and the way it is split
+// into tiles, or by the tile image for collections of images. Images embedded in the map are
+// identified by their content.
+func tileCacheSource(ts *tiled.Tileset, id uint32) string {
+	switch {
+	case len(ts.Source) > 0:
+		return filepath.Join(ts.BaseDir(), filepath.Base(ts.Source))
+	case ts.Image != nil:
+		return fmt.Sprintf("%s@%dx%d+%d+%d", imageCacheSource(ts.GetFileFullPath(ts.Image.Source), ts.Image), ts.TileWidth, ts.TileHeight, ts.Spacing, ts.Margin)
+	}
+	if t, err := ts.GetTilesetTile(id); err == nil && t.Image != nil {
+		return imageCacheSource(ts.GetFileFullPath(t.Image.Source), t.Image)
+	}
+	return ""
+}
+
+// tileCacheRect returns the sub-rectangle of the tile image for tiles of collections of images embedded
+// in a map, as tiles with the same ID can use different parts of the same image in each of them.
+func tileCacheRect(ts *tiled.Tileset, id uint32) image.Rectangle {
+	if len(ts.Source) > 0 || ts.Image != nil {
+		return image.Rectangle{}
+	}
+	if t, err := ts.GetTilesetTile(id); err == nil && t.Width > 0 && t.Height > 0 {
+		return image.Rect(t.X, t.Y, t.X+t.Width, t.Y+t.Height)
+	}
+	return image.Rectangle{}
+}
+
+// imageCacheSource returns the file of the image, followed by its transparent color if it has one.
+func imageCacheSource(fileName string, img *tiled.Image) string {
+	source := fileName
+	if img.IsEmbedded() {
+		source = fmt.Sprintf("embedded:%s:%s:%s:%x", img.Format, img.Data.Encoding, img.Data.Compression, sha256.Sum256(img.Data.RawData))
+	}
+	if img.Trans != nil {
+		source += img.Trans.String()
+	}
+	return source
+}
+
+// tileImage returns the cached tile image. Images that are not cached are loaded using the load
+// function, which returns images of the requested tile and any other tiles of the tileset by their ID.
+// Errors are not cached, so loading is retried the next time.
+func (c *TileCache) tileImage(ts *tiled.Tileset, id uint32, load func(ts *tiled.Tileset, id uint32) (map[uint32]image.Image, error)) (image.Image, error) {
+	source := tileCacheSource(ts, id)
+	key := tileCacheKey{source: source, id: id, rect: tileCacheRect(ts, id)}
+	return c.cachedImage(key, func() (map[tileCacheKey]image.Image, error) {
+		images, err := load(ts, id)
+		if err != nil {
+			return nil, err
+		}
+
+		keyed := make(map[tileCacheKey]image.Image, len(images))
+		for i, img := range images {
+			keyed[tileCacheKey{source: source, id: i, rect: tileCacheRect(ts, i)}] = img
+		}
+		return keyed, nil
+	})
+}
+
+// layerImage returns the cached image of an image layer, loading it using the load function if it is
+// not cached. Whole images are keyed the same way as images of collection tiles without sub-rectangles,
+// so such images are shared by image layers and tiles.
+func (c *TileCache) layerImage(fileName string, img *tiled.Image, load func() (image.Image, error)) (image.Image, error) {
+	key := tileCacheKey{source: imageCacheSource(fileName, img)}
+	return c.cachedImage(key, func() (map[tileCacheKey]image.Image, error) {
+		loaded, err := load()
+		if err != nil {
+			return nil, err
+		}
+		return map[tileCacheKey]image.Image{key: loaded}, nil
+	})
+}
+
+// cachedImage returns the cached image of the key. Images that are not cached are loaded using the
+// load function, which returns images of the key and any other keys of the same source. Images of
+// the same source are loaded only once at a time.
+func (c *TileCache) cachedImage(key tileCacheKey, load func() (map[tileCacheKey]image.Image, error)) (image.Image, error) {
+	c.mu.Lock()
+	img, ok := c.images[key]
+	loading := c.loading[key.source]
+	if loading == nil {
+		loading = &sync.Mutex{}
+		c.loading[key.source] = loading
+	}
+	c.mu.Unlock()
+	if ok {
+		return img, nil
+	}
+
+	loading.Lock()
+	defer loading.Unlock()
+
+	// Image could have been loaded while waiting for the lock
+	c.mu.Lock()
+	img, ok = c.images[key]
+	c.mu.Unlock()
+	if ok {
+		return img, nil
+	}
+
+	images, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, img := range images {
+		c.images[k] = img
+	}
+	return images[key], nil
+}
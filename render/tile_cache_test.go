@@ -0,0 +1,129 @@
+/*
+Copyright (c) 2023 Lauris Bukšis-Haberkorns <lauris@nix.lv>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package render
+
+import (
+	"image"
+	"io/fs"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/disintegration/imaging"
+	"github.com/lafriks/go-tiled"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingFS opens files from the operating system and counts how many times each file was opened.
+type countingFS struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func (f *countingFS) Open(name string) (fs.File, error) {
+	f.mu.Lock()
+	f.counts[name]++
+	f.mu.Unlock()
+	return os.Open(name)
+}
+
+func TestTileCacheShared(t *testing.T) {
+	files := &countingFS{counts: make(map[string]int)}
+	cache := NewTileCache()
+
+	var renders int32
+	var wg sync.WaitGroup
+	for _, name := range []string{"test_wangsets_map.tmx", "test_hexagonal.tmx", "test_render_objects.tmx", "test_wangsets_map.tmx"} {
+		m, err := tiled.LoadFile("../assets/" + name)
+		assert.NoError(t, err)
+
+		r, err := NewRendererWithFileSystem(m, files)
+		assert.NoError(t, err)
+		r.TileCache = cache
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			dst := image.NewNRGBA(image.Rect(0, 0, 64, 64))
+			if assert.NoError(t, r.RenderRegion(dst, image.Rect(32, 32, 96, 96))) {
+				atomic.AddInt32(&renders, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(4), renders)
+
+	// All maps use the same tileset, which image is decoded only once
+	assert.Len(t, files.counts, 1)
+	for name, count := range files.counts {
+		assert.Equal(t, 1, count, name)
+	}
+}
+
+func TestTileCacheSharedImage(t *testing.T) {
+	m, err := tiled.LoadFile("../assets/test_tile_cache.tmx")
+	assert.NoError(t, err)
+
+	r, err := NewRenderer(m)
+	assert.NoError(t, err)
+
+	// Tilesets embedded in the map use the same image and tile IDs, but split it in different ways
+	expected := []image.Rectangle{image.Rect(0, 0, 4, 4), image.Rect(0, 0, 2, 2), image.Rect(0, 0, 4, 4), image.Rect(2, 2, 6, 6)}
+	for i, tile := range m.Layers[0].Tiles {
+		assert.Equal(t, uint32(0), tile.ID)
+
+		img, err := r.LoadTilesetImage(tile.Tileset, m.Tilesets[0].Image)
+		assert.NoError(t, err)
+		timg, err := r.getTileImage(tile)
+		if assert.NoError(t, err) {
+			assert.Equal(t, imaging.Crop(img, expected[i]).Pix, imaging.Clone(timg).Pix, "tileset %s", tile.Tileset.Name)
+		}
+	}
+}
+
+func TestTileCacheEmbeddedImages(t *testing.T) {
+	cache := NewTileCache()
+	loadTile := func() image.Image {
+		m, err := tiled.LoadFile("../assets/test_image_embedded.tmx")
+		assert.NoError(t, err)
+
+		r, err := NewRenderer(m)
+		assert.NoError(t, err)
+		r.TileCache = cache
+
+		tile, err := r.getTileImage(m.Layers[0].Tiles[1*m.Width+1])
+		assert.NoError(t, err)
+		return tile
+	}
+
+	// Embedded images of separately loaded maps are identified by their content
+	first := loadTile()
+	assert.Same(t, first, loadTile())
+
+	// Images are loaded again after clearing the cache
+	cache.Clear()
+	again := loadTile()
+	assert.NotSame(t, first, again)
+	assert.Equal(t, imaging.Clone(first).Pix, imaging.Clone(again).Pix)
+}
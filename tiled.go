@@ -23,21 +23,24 @@ SOFTWARE.
 package tiled
 
 import (
+	"bufio"
+	"encoding/json"
 	"encoding/xml"
 	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
-// LoadReader function loads tiled map in TMX format from io.Reader
+// LoadReader function loads tiled map in TMX or JSON format from io.Reader
 // baseDir is used for loading additional tile data, current directory is used if empty
 func LoadReader(baseDir string, r io.Reader, options ...LoaderOption) (*Map, error) {
 	l := newLoader(options...)
 	return l.LoadReader(baseDir, r)
 }
 
-// LoadFile function loads tiled map in TMX format from file
+// LoadFile function loads tiled map in TMX or JSON format from file
 func LoadFile(fileName string, options ...LoaderOption) (*Map, error) {
 	l := newLoader(options...)
 	return l.LoadFile(fileName)
@@ -79,9 +82,33 @@ func WithFileSystem(fileSystem fs.FS) LoaderOption {
 	}
 }
 
-// LoadReader function loads tiled map in TMX format from io.Reader
+// LoadReader function loads tiled map in TMX or JSON format from io.Reader
 // baseDir is used for loading additional tile data, current directory is used if empty
 func (l *loader) LoadReader(baseDir string, r io.Reader) (*Map, error) {
+	br := bufio.NewReader(r)
+	if sniffFormat(br) == formatJSON {
+		return l.loadJSON(baseDir, br)
+	}
+	return l.loadXML(baseDir, br)
+}
+
+// LoadFile function loads tiled map in TMX or JSON format from file
+func (l *loader) LoadFile(fileName string) (*Map, error) {
+	f, err := l.open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	dir := filepath.Dir(fileName)
+	br := bufio.NewReader(f)
+	if detectFormat(fileName, br) == formatJSON {
+		return l.loadJSON(dir, br)
+	}
+	return l.loadXML(dir, br)
+}
+
+func (l *loader) loadXML(baseDir string, r io.Reader) (*Map, error) {
 	d := xml.NewDecoder(r)
 
 	m := &Map{
@@ -95,14 +122,71 @@ func (l *loader) LoadReader(baseDir string, r io.Reader) (*Map, error) {
 	return m, nil
 }
 
-// LoadFile function loads tiled map in TMX format from file
-func (l *loader) LoadFile(fileName string) (*Map, error) {
-	f, err := l.open(fileName)
+func (l *loader) loadJSON(baseDir string, r io.Reader) (*Map, error) {
+	item := jsonMap{}
+	item.SetDefaults()
+
+	if err := json.NewDecoder(r).Decode(&item); err != nil {
+		return nil, err
+	}
+
+	m, err := item.toMap()
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
+	m.loader = l
+	m.baseDir = baseDir
 
-	dir := filepath.Dir(fileName)
-	return l.LoadReader(dir, f)
+	if err := m.decodeLayers(); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// documentFormat is the format a Tiled document is stored in.
+type documentFormat int
+
+const (
+	formatUnknown documentFormat = iota
+	formatXML
+	formatJSON
+)
+
+// formatFromFileName detects document format by the file extension.
+func formatFromFileName(fileName string) documentFormat {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".tmx", ".tsx", ".tx", ".xml":
+		return formatXML
+	case ".tmj", ".tsj", ".tj", ".json":
+		return formatJSON
+	}
+	return formatUnknown
+}
+
+// detectFormat detects document format by the file extension, falling back
+// to the content if the extension is not known.
+func detectFormat(fileName string, r *bufio.Reader) documentFormat {
+	if format := formatFromFileName(fileName); format != formatUnknown {
+		return format
+	}
+	return sniffFormat(r)
+}
+
+// sniffFormat detects document format by the first non-whitespace character
+// without consuming any input.
+func sniffFormat(r *bufio.Reader) documentFormat {
+	for n := 1; ; n++ {
+		buf, err := r.Peek(n)
+		if err != nil {
+			return formatXML
+		}
+		switch buf[n-1] {
+		case ' ', '\t', '\r', '\n':
+			continue
+		case '{':
+			return formatJSON
+		}
+		return formatXML
+	}
 }
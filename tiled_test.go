@@ -25,6 +25,7 @@ package tiled
 import (
 	"bytes"
 	"embed"
+	"encoding/json"
 	"encoding/xml"
 	"image/color"
 	"io/fs"
@@ -153,6 +154,31 @@ func TestImageLayer(t *testing.T) {
 	assert.NotNil(t, image)
 
 	assert.Equal(t, image.Source, "background.jpg", image.Source)
+	assert.False(t, layer.RepeatX)
+	assert.False(t, layer.RepeatY)
+
+	m, err = LoadFile(filepath.Join(GetAssetsDirectory(), "test_image_layer.tmx"))
+	assert.NoError(t, err)
+	if assert.Len(t, m.ImageLayers, 2) {
+		assert.Equal(t, "#ff00ff", m.ImageLayers[0].Image.Trans.String())
+		assert.True(t, m.ImageLayers[1].RepeatX)
+		assert.False(t, m.ImageLayers[1].RepeatY)
+	}
+
+	_, err = m.ImageLayers[0].Image.DecodeData()
+	assert.ErrorIs(t, err, ErrNoImageData)
+
+	m, err = LoadFile(filepath.Join(GetAssetsDirectory(), "test_image_embedded.tmx"))
+	assert.NoError(t, err)
+	for _, image := range []*Image{m.Tilesets[0].Image, m.ImageLayers[0].Image} {
+		assert.True(t, image.IsEmbedded())
+		assert.Equal(t, "png", image.Format)
+		decoded, err := image.DecodeData()
+		if assert.NoError(t, err) {
+			assert.Equal(t, 8, decoded.Bounds().Dx())
+			assert.Equal(t, 8, decoded.Bounds().Dy())
+		}
+	}
 }
 
 func TestGroup(t *testing.T) {
@@ -177,6 +203,7 @@ func TestGroup(t *testing.T) {
 	assert.Len(t, b.Groups, 1)
 
 	bL := b.Layers[0]
+	assert.Same(t, bL, m.GetLayerByName("Tile Layer 2"))
 	assert.Equal(t, uint32(3), bL.ID)
 	assert.Equal(t, bL.Name, "Tile Layer 2")
 	assert.Len(t, bL.Tiles, 400)
@@ -188,6 +215,218 @@ func TestGroup(t *testing.T) {
 	assert.Len(t, c.Groups, 0)
 }
 
+func TestLayerOrder(t *testing.T) {
+	for _, fileName := range []string{"groups.tmx", "groups.tmj"} {
+		m, err := LoadFile(filepath.Join(GetAssetsDirectory(), fileName))
+		assert.NoError(t, err)
+
+		assert.Len(t, m.Children, 2)
+		assert.Equal(t, LayerKindTile, m.Children[0].Kind())
+		assert.Equal(t, LayerKindGroup, m.Children[1].Kind())
+
+		// Image layer is stored after group B in group A
+		a := m.Children[1].(*Group)
+		assert.Same(t, m.Groups[0], a)
+		assert.Len(t, a.Children, 2)
+		assert.Equal(t, "B", a.Children[0].GetName())
+		assert.Equal(t, LayerKindImage, a.Children[1].Kind())
+		assert.Same(t, a.ImageLayers[0], a.Children[1])
+	}
+
+	m, err := LoadFile(filepath.Join(GetAssetsDirectory(), "test_layer_order.tmx"))
+	assert.NoError(t, err)
+
+	var names []string
+	for _, l := range m.Children {
+		names = append(names, l.GetName())
+	}
+	assert.Equal(t, []string{"Bottom", "Objects", "Top", "Image"}, names)
+	assert.False(t, m.Children[3].IsVisible())
+}
+
+func TestInfiniteNegativeChunks(t *testing.T) {
+	m, err := LoadFile(filepath.Join(GetAssetsDirectory(), "test_infinite_negative.tmx"))
+	assert.NoError(t, err)
+
+	assert.Equal(t, &Border{MinX: -16, MinY: -16, MaxX: 15, MaxY: 15, Width: 32, Height: 32, Square: 1024}, m.Border)
+	assert.Equal(t, 32, m.Width)
+	assert.Equal(t, 32, m.Height)
+
+	l := m.Layers[0]
+	assert.Len(t, l.Tiles, 32*32)
+	assert.False(t, l.IsEmpty())
+
+	// Tiles are stored relative to the top-left corner of the map border
+	tile := l.Tiles[15*32+15]
+	assert.Equal(t, uint32(0), tile.ID)
+	assert.Equal(t, -1, tile.X)
+	assert.Equal(t, -1, tile.Y)
+	assert.Equal(t, uint32(20), l.Tiles[16*32+16].ID)
+	assert.Equal(t, uint32(2), l.Tiles[17*32+18].ID)
+	assert.True(t, l.Tiles[0].IsNil())
+
+	assert.Equal(t, &Border{MinX: -1, MinY: -1, MaxX: 2, MaxY: 1, Width: 4, Height: 3, Square: 12}, l.Border)
+}
+
+func TestNestedGroups(t *testing.T) {
+	m, err := LoadFile(filepath.Join(GetAssetsDirectory(), "test_nested_groups.tmx"))
+	assert.NoError(t, err)
+
+	assert.Len(t, m.AllLayers, 2)
+	assert.Equal(t, "Ground", m.AllLayers[0].Name)
+	assert.Equal(t, "Deep", m.AllLayers[1].Name)
+	assert.Same(t, m.AllLayers[1], m.GetLayerByName("Deep"))
+	assert.Same(t, m.AllLayers[1], m.Groups[0].GetLayerByName("Deep"))
+	assert.Len(t, m.AllLayers[1].Tiles, 4)
+
+	// Object groups in nested groups are decoded
+	o := m.Groups[0].Groups[0].ObjectGroups[0].Objects[0]
+	assert.True(t, o.TemplateLoaded)
+	assert.NotNil(t, o.Template)
+
+	var names []string
+	states := map[string]*LayerState{}
+	err = m.WalkLayers(func(l MapLayer, state *LayerState) error {
+		names = append(names, l.GetName())
+		states[l.GetName()] = state
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Ground", "Outer", "Inner", "Deep", "Deep Objects", "Image"}, names)
+
+	assert.Equal(t, &LayerState{Opacity: 1, Visible: true, ParallaxX: 1, ParallaxY: 1}, states["Ground"])
+	assert.Equal(t, &LayerState{
+		Parents:   []*Group{m.Groups[0], m.Groups[0].Groups[0]},
+		Opacity:   0.125,
+		Visible:   false,
+		OffsetX:   12,
+		OffsetY:   6,
+		ParallaxX: 0.25,
+		ParallaxY: 2,
+	}, states["Deep"])
+	assert.Equal(t, &LayerState{
+		Parents:   []*Group{m.Groups[0]},
+		Opacity:   0.5,
+		Visible:   true,
+		OffsetX:   10,
+		OffsetY:   5,
+		ParallaxX: 0.5,
+		ParallaxY: 1,
+	}, states["Image"])
+
+	names = nil
+	err = m.WalkLayers(func(l MapLayer, state *LayerState) error {
+		names = append(names, l.GetName())
+		if l.GetName() == "Inner" {
+			return SkipGroup
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Ground", "Outer", "Inner", "Image"}, names)
+
+	names = nil
+	err = m.Groups[0].WalkLayers(func(l MapLayer, state *LayerState) error {
+		names = append(names, l.GetName())
+		return ErrInvalidTileGID
+	})
+	assert.ErrorIs(t, err, ErrInvalidTileGID)
+	assert.Equal(t, []string{"Inner"}, names)
+}
+
+func TestTintAndParallax(t *testing.T) {
+	m, err := LoadFile(filepath.Join(GetAssetsDirectory(), "test_render_tint.tmx"))
+	assert.NoError(t, err)
+
+	assert.Equal(t, 8.0, m.ParallaxOriginX)
+	assert.Equal(t, 4.0, m.ParallaxOriginY)
+
+	group := m.Groups[0]
+	orange := NewHexColor(255, 128, 64, 255)
+	assert.Equal(t, &orange, group.TintColor)
+	assert.Equal(t, float32(0.5), group.Opacity)
+
+	far := group.Layers[0]
+	halfWhite := NewHexColor(255, 255, 255, 128)
+	assert.Equal(t, &halfWhite, far.TintColor)
+	assert.Equal(t, 0.5, far.ParallaxX)
+	assert.Equal(t, 0.25, far.ParallaxY)
+	assert.Nil(t, group.ObjectGroups[0].TintColor)
+
+	states := map[string]*LayerState{}
+	err = m.WalkLayers(func(l MapLayer, state *LayerState) error {
+		states[l.GetName()] = state
+		return nil
+	})
+	assert.NoError(t, err)
+
+	combined := NewHexColor(255, 128, 64, 128)
+	assert.Equal(t, &LayerState{
+		Parents:   []*Group{group},
+		Opacity:   0.5,
+		Visible:   true,
+		ParallaxX: 0.5,
+		ParallaxY: 0.25,
+		TintColor: &combined,
+	}, states["Far"])
+	assert.Equal(t, &orange, states["Shapes"].TintColor)
+	assert.Equal(t, float32(0.5), states["Shapes"].Opacity)
+}
+
+func TestTilesetRenderOptions(t *testing.T) {
+	m, err := LoadFile(filepath.Join(GetAssetsDirectory(), "test_render_alignment.tmx"))
+	assert.NoError(t, err)
+
+	grid := m.Tilesets[0]
+	assert.Equal(t, ObjectAlignmentUnspecified, grid.ObjectAlignment)
+	assert.Equal(t, TileRenderSizeGrid, grid.TileRenderSize)
+	assert.Equal(t, FillModeStretch, grid.FillMode)
+
+	centered := m.Tilesets[1]
+	assert.Equal(t, ObjectAlignmentCenter, centered.ObjectAlignment)
+	assert.Equal(t, TileRenderSizeTile, centered.TileRenderSize)
+	assert.Equal(t, FillModePreserveAspectFit, centered.FillMode)
+
+	// External tilesets keep the first GID and source of the map tileset
+	m, err = LoadFile(filepath.Join(GetAssetsDirectory(), "test2.tmx"))
+	assert.NoError(t, err)
+	ts := m.Tilesets[0]
+	assert.True(t, ts.SourceLoaded)
+	assert.Equal(t, uint32(1), ts.FirstGID)
+	assert.Equal(t, "tilesets/test2.tsx", ts.Source)
+	assert.Equal(t, TileRenderSizeTile, ts.TileRenderSize)
+	assert.Nil(t, ts.Grid)
+
+	m, err = LoadFile(filepath.Join(GetAssetsDirectory(), "racing.tmx"))
+	assert.NoError(t, err)
+	assert.Equal(t, &TilesetGrid{Orientation: "orthogonal", Width: 1, Height: 1}, m.Tilesets[0].Grid)
+}
+
+func TestHexagonalRotation(t *testing.T) {
+	m, err := LoadFile(filepath.Join(GetAssetsDirectory(), "test_hexagonal_rotation.tmx"))
+	assert.NoError(t, err)
+
+	assert.Equal(t, &TilesetTransformations{HFlip: true, Rotate: true, PreferUntransformed: true}, m.Tilesets[0].Transformations)
+
+	tiles := m.Layers[0].Tiles
+	assert.Equal(t, uint32(0), tiles[0].ID)
+	assert.False(t, tiles[0].RotatedHexagonal120)
+	assert.Equal(t, uint32(20), tiles[1].ID)
+	assert.True(t, tiles[1].RotatedHexagonal120)
+	assert.False(t, tiles[1].DiagonalFlip)
+	assert.Equal(t, uint32(0), tiles[3].ID)
+	assert.True(t, tiles[3].DiagonalFlip)
+	assert.False(t, tiles[3].RotatedHexagonal120)
+	assert.Equal(t, uint32(2), tiles[4].ID)
+	assert.True(t, tiles[4].DiagonalFlip)
+	assert.True(t, tiles[4].RotatedHexagonal120)
+
+	var ts jsonTileset
+	err = json.Unmarshal([]byte(`{"transformations": {"hflip": false, "vflip": true, "rotate": true, "preferuntransformed": false}}`), &ts)
+	assert.NoError(t, err)
+	assert.Equal(t, &TilesetTransformations{VFlip: true, Rotate: true}, ts.toTileset().Transformations)
+}
+
 func TestFont(t *testing.T) {
 	m, err := LoadFile(filepath.Join(GetAssetsDirectory(), "font.tmx"))
 
@@ -440,3 +679,84 @@ func TestVersions(t *testing.T) {
 	assert.Equal(t, tileset.Version, "1.2")
 	assert.Equal(t, tileset.TiledVersion, "1.2.3")
 }
+
+// clearLayerData drops raw layer data that depends on the source format.
+func clearLayerData(layers []*Layer, groups []*Group) {
+	for _, l := range layers {
+		l.data = nil
+	}
+	for _, g := range groups {
+		clearLayerData(g.Layers, g.Groups)
+	}
+}
+
+func TestLoadJSON(t *testing.T) {
+	for _, name := range []string{"test2", "groups", "test_nested_groups"} {
+		t.Run(name, func(t *testing.T) {
+			tmx, err := LoadFile(filepath.Join(GetAssetsDirectory(), name+".tmx"))
+			assert.NoError(t, err)
+
+			tmj, err := LoadFile(filepath.Join(GetAssetsDirectory(), name+".tmj"))
+			assert.NoError(t, err)
+
+			clearLayerData(tmx.Layers, tmx.Groups)
+			clearLayerData(tmj.Layers, tmj.Groups)
+			assert.Equal(t, tmx, tmj)
+		})
+	}
+}
+
+func TestLoadReaderJSON(t *testing.T) {
+	r := bytes.NewBufferString(` {"type": "map", "version": 1, "width": 2, "height": 1, "tilewidth": 16, "tileheight": 16,
+"orientation": "orthogonal", "infinite": false, "properties": [{"name": "speed", "type": "float", "value": 1.5}],
+"layers": [{"type": "tilelayer", "id": 1, "name": "Tile Layer 1", "visible": false, "data": [0, 0]}]}`)
+	m, err := LoadReader(GetAssetsDirectory(), r)
+
+	assert.NoError(t, err)
+	if assert.NotNil(t, m) {
+		assert.Equal(t, "1", m.Version)
+		assert.Equal(t, "right-down", m.RenderOrder)
+		assert.Equal(t, 1.5, m.Properties.GetFloat("speed"))
+		if assert.Len(t, m.Layers, 1) {
+			assert.False(t, m.Layers[0].Visible)
+			assert.Equal(t, float32(1), m.Layers[0].Opacity)
+			assert.Len(t, m.Layers[0].Tiles, 2)
+			assert.True(t, m.Layers[0].IsEmptySlowly())
+		}
+	}
+}
+
+func TestLoadJSONReferences(t *testing.T) {
+	m, err := LoadFile(filepath.Join(GetAssetsDirectory(), "test_json_references.tmx"))
+	assert.NoError(t, err)
+	if !assert.NotNil(t, m) {
+		return
+	}
+
+	tmx, err := LoadFile(filepath.Join(GetAssetsDirectory(), "test2.tmx"))
+	assert.NoError(t, err)
+
+	if assert.Len(t, m.Tilesets, 1) {
+		tileset := *m.Tilesets[0]
+		assert.True(t, tileset.SourceLoaded)
+		assert.Equal(t, uint32(1), tileset.FirstGID)
+		assert.Equal(t, "tilesets/test2.tsj", tileset.Source)
+
+		tileset.Source = tmx.Tilesets[0].Source
+		assert.Equal(t, tmx.Tilesets[0], &tileset)
+	}
+
+	if assert.Len(t, m.ObjectGroups, 1) && assert.Len(t, m.ObjectGroups[0].Objects, 3) {
+		tx := m.ObjectGroups[0].Objects[0]
+		tj := m.ObjectGroups[0].Objects[1]
+
+		assert.True(t, tx.TemplateLoaded)
+		assert.True(t, tj.TemplateLoaded)
+		if assert.NotNil(t, tj.Template) && assert.NotNil(t, tj.Template.Object) {
+			assert.Equal(t, "door", tj.Template.Object.Name)
+			assert.True(t, tj.Template.Object.Properties.GetBool("locked"))
+			assert.True(t, tj.Template.Tileset.SourceLoaded)
+		}
+		assert.Equal(t, tx.Template, tj.Template)
+	}
+}
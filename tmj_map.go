@@ -0,0 +1,370 @@
+/*
+Copyright (c) 2023 Lauris Bukšis-Haberkorns <lauris@nix.lv>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package tiled
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ErrUnknownLayerType error is returned when JSON map contains layer of unknown type
+var ErrUnknownLayerType = errors.New("tiled: unknown layer type")
+
+// jsonMap is a map as stored in JSON format (.tmj).
+type jsonMap struct {
+	Version          jsonVersion      `json:"version"`
+	TiledVersion     string           `json:"tiledversion"`
+	Class            string           `json:"class"`
+	Orientation      string           `json:"orientation"`
+	RenderOrder      string           `json:"renderorder"`
+	Width            int              `json:"width"`
+	Height           int              `json:"height"`
+	TileWidth        int              `json:"tilewidth"`
+	TileHeight       int              `json:"tileheight"`
+	HexSideLength    int              `json:"hexsidelength"`
+	StaggerAxis      Axis             `json:"staggeraxis"`
+	StaggerIndex     StaggerIndexType `json:"staggerindex"`
+	BackgroundColor  *HexColor        `json:"backgroundcolor"`
+	ParallaxOriginX  float64          `json:"parallaxoriginx"`
+	ParallaxOriginY  float64          `json:"parallaxoriginy"`
+	NextLayerID      uint32           `json:"nextlayerid"`
+	NextObjectID     uint32           `json:"nextobjectid"`
+	Infinite         bool             `json:"infinite"`
+	CompressionLevel int              `json:"compressionlevel"`
+	Properties       jsonProperties   `json:"properties"`
+	Tilesets         []*jsonTileset   `json:"tilesets"`
+	Layers           []*jsonLayer     `json:"layers"`
+}
+
+// SetDefaults provides default values for jsonMap.
+func (m *jsonMap) SetDefaults() {
+	m.RenderOrder = "right-down"
+	m.CompressionLevel = -1
+}
+
+func (m *jsonMap) toMap() (*Map, error) {
+	item := &Map{
+		Version:          string(m.Version),
+		TiledVersion:     m.TiledVersion,
+		Class:            m.Class,
+		Orientation:      m.Orientation,
+		RenderOrder:      m.RenderOrder,
+		Width:            m.Width,
+		Height:           m.Height,
+		TileWidth:        m.TileWidth,
+		TileHeight:       m.TileHeight,
+		HexSideLength:    m.HexSideLength,
+		StaggerAxis:      m.StaggerAxis,
+		StaggerIndex:     m.StaggerIndex,
+		BackgroundColor:  m.BackgroundColor,
+		ParallaxOriginX:  m.ParallaxOriginX,
+		ParallaxOriginY:  m.ParallaxOriginY,
+		NextLayerID:      m.NextLayerID,
+		NextObjectID:     m.NextObjectID,
+		IsInfinite:       m.Infinite,
+		CompressionLevel: m.CompressionLevel,
+	}
+
+	if props := m.Properties.toProperties(); props != nil {
+		item.Properties = &props
+	}
+
+	for _, ts := range m.Tilesets {
+		item.Tilesets = append(item.Tilesets, ts.toTileset())
+	}
+
+	var err error
+	if item.Children, err = decodeJSONLayers(m.Layers); err != nil {
+		return nil, err
+	}
+	item.Layers, item.ObjectGroups, item.ImageLayers, item.Groups = item.Children.split()
+
+	return item, nil
+}
+
+// jsonLayer is any kind of layer as stored in JSON format. The Type field
+// determines which of the fields are in use.
+type jsonLayer struct {
+	Type       string         `json:"type"`
+	ID         uint32         `json:"id"`
+	Name       string         `json:"name"`
+	Class      string         `json:"class"`
+	Opacity    float32        `json:"opacity"`
+	Visible    bool           `json:"visible"`
+	OffsetX    float64        `json:"offsetx"`
+	OffsetY    float64        `json:"offsety"`
+	ParallaxX  float64        `json:"parallaxx"`
+	ParallaxY  float64        `json:"parallaxy"`
+	TintColor  *HexColor      `json:"tintcolor"`
+	X          int            `json:"x"`
+	Y          int            `json:"y"`
+	Properties jsonProperties `json:"properties"`
+
+	// Tile layer
+	Data        json.RawMessage `json:"data"`
+	Encoding    string          `json:"encoding"`
+	Compression string          `json:"compression"`
+	Chunks      []*jsonChunk    `json:"chunks"`
+
+	// Object group
+	Color     *HexColor     `json:"color"`
+	DrawOrder string        `json:"draworder"`
+	Objects   []*jsonObject `json:"objects"`
+
+	// Image layer
+	Image            string    `json:"image"`
+	ImageWidth       int       `json:"imagewidth"`
+	ImageHeight      int       `json:"imageheight"`
+	TransparentColor *HexColor `json:"transparentcolor"`
+	RepeatX          bool      `json:"repeatx"`
+	RepeatY          bool      `json:"repeaty"`
+
+	// Group
+	Layers []*jsonLayer `json:"layers"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (l *jsonLayer) UnmarshalJSON(data []byte) error {
+	type aliasJSONLayer jsonLayer
+	item := aliasJSONLayer{
+		Opacity:   1,
+		Visible:   true,
+		ParallaxX: 1,
+		ParallaxY: 1,
+	}
+
+	if err := json.Unmarshal(data, &item); err != nil {
+		return err
+	}
+
+	*l = (jsonLayer)(item)
+	return nil
+}
+
+// decodeJSONLayers converts layers of all types to their TMX counterparts
+// keeping their order.
+func decodeJSONLayers(src []*jsonLayer) (MapLayers, error) {
+	layers := make(MapLayers, 0, len(src))
+	for _, jl := range src {
+		switch LayerKind(jl.Type) {
+		case LayerKindTile:
+			l, err := jl.toLayer()
+			if err != nil {
+				return nil, err
+			}
+			layers = append(layers, l)
+		case LayerKindObjectGroup:
+			layers = append(layers, jl.toObjectGroup())
+		case LayerKindImage:
+			layers = append(layers, jl.toImageLayer())
+		case LayerKindGroup:
+			g, err := jl.toGroup()
+			if err != nil {
+				return nil, err
+			}
+			layers = append(layers, g)
+		default:
+			return nil, ErrUnknownLayerType
+		}
+	}
+	return layers, nil
+}
+
+func (l *jsonLayer) toLayer() (*Layer, error) {
+	item := &Layer{
+		ID:          l.ID,
+		Name:        l.Name,
+		Class:       l.Class,
+		Opacity:     l.Opacity,
+		Visible:     l.Visible,
+		OffsetX:     int(l.OffsetX),
+		OffsetY:     int(l.OffsetY),
+		ParallaxX:   l.ParallaxX,
+		ParallaxY:   l.ParallaxY,
+		TintColor:   l.TintColor,
+		Properties:  l.Properties.toProperties(),
+		Encoding:    l.dataEncoding(),
+		Compression: l.Compression,
+	}
+
+	if l.Chunks != nil {
+		item.data = &Data{
+			Encoding:    item.Encoding,
+			Compression: item.Compression,
+		}
+		for _, c := range l.Chunks {
+			rawData, err := l.rawData(c.Data)
+			if err != nil {
+				return nil, err
+			}
+			item.Chunks = append(item.Chunks, &Chunk{
+				X:       c.X,
+				Y:       c.Y,
+				Width:   c.Width,
+				Height:  c.Height,
+				RawData: rawData,
+			})
+		}
+		return item, nil
+	}
+
+	if l.Data == nil {
+		return item, nil
+	}
+
+	rawData, err := l.rawData(l.Data)
+	if err != nil {
+		return nil, err
+	}
+	item.data = &Data{
+		Encoding:    item.Encoding,
+		Compression: item.Compression,
+		RawData:     rawData,
+	}
+
+	return item, nil
+}
+
+// dataEncoding returns TMX data encoding that matches the layer data. Data stored
+// as an array of GIDs is converted to CSV.
+func (l *jsonLayer) dataEncoding() string {
+	if l.Encoding == "base64" {
+		return "base64"
+	}
+	return "csv"
+}
+
+// rawData converts JSON layer data to the raw data of the matching TMX encoding.
+func (l *jsonLayer) rawData(data json.RawMessage) ([]byte, error) {
+	if l.Encoding == "base64" {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return nil, err
+		}
+		return []byte(s), nil
+	}
+
+	var gids []uint32
+	if err := json.Unmarshal(data, &gids); err != nil {
+		return nil, err
+	}
+
+	var sb strings.Builder
+	for i, gid := range gids {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(strconv.FormatUint(uint64(gid), 10))
+	}
+	return []byte(sb.String()), nil
+}
+
+func (l *jsonLayer) toObjectGroup() *ObjectGroup {
+	item := &ObjectGroup{
+		ID:         l.ID,
+		Name:       l.Name,
+		Class:      l.Class,
+		Color:      l.Color,
+		Opacity:    l.Opacity,
+		Visible:    l.Visible,
+		OffsetX:    int(l.OffsetX),
+		OffsetY:    int(l.OffsetY),
+		ParallaxX:  l.ParallaxX,
+		ParallaxY:  l.ParallaxY,
+		TintColor:  l.TintColor,
+		DrawOrder:  l.DrawOrder,
+		Properties: l.Properties.toProperties(),
+	}
+
+	for _, o := range l.Objects {
+		item.Objects = append(item.Objects, o.toObject())
+	}
+
+	return item
+}
+
+func (l *jsonLayer) toImageLayer() *ImageLayer {
+	item := &ImageLayer{
+		ID:         l.ID,
+		Name:       l.Name,
+		Class:      l.Class,
+		OffsetX:    int(l.OffsetX),
+		OffsetY:    int(l.OffsetY),
+		ParallaxX:  l.ParallaxX,
+		ParallaxY:  l.ParallaxY,
+		TintColor:  l.TintColor,
+		X:          l.X,
+		Y:          l.Y,
+		Opacity:    l.Opacity,
+		Visible:    l.Visible,
+		RepeatX:    l.RepeatX,
+		RepeatY:    l.RepeatY,
+		Properties: l.Properties.toProperties(),
+	}
+
+	if len(l.Image) > 0 {
+		item.Image = &Image{
+			Source: l.Image,
+			Trans:  l.TransparentColor,
+			Width:  l.ImageWidth,
+			Height: l.ImageHeight,
+		}
+	}
+
+	return item
+}
+
+func (l *jsonLayer) toGroup() (*Group, error) {
+	item := &Group{
+		ID:         l.ID,
+		Name:       l.Name,
+		Class:      l.Class,
+		OffsetX:    int(l.OffsetX),
+		OffsetY:    int(l.OffsetY),
+		ParallaxX:  l.ParallaxX,
+		ParallaxY:  l.ParallaxY,
+		TintColor:  l.TintColor,
+		Opacity:    l.Opacity,
+		Visible:    l.Visible,
+		Properties: l.Properties.toProperties(),
+	}
+
+	var err error
+	if item.Children, err = decodeJSONLayers(l.Layers); err != nil {
+		return nil, err
+	}
+	item.Layers, item.ObjectGroups, item.ImageLayers, item.Groups = item.Children.split()
+
+	return item, nil
+}
+
+// jsonChunk is a chunk of an infinite map tile layer as stored in JSON format.
+type jsonChunk struct {
+	X      int             `json:"x"`
+	Y      int             `json:"y"`
+	Width  int             `json:"width"`
+	Height int             `json:"height"`
+	Data   json.RawMessage `json:"data"`
+}
@@ -0,0 +1,156 @@
+/*
+Copyright (c) 2023 Lauris Bukšis-Haberkorns <lauris@nix.lv>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package tiled
+
+import "encoding/json"
+
+// jsonObject is an object as stored in JSON format.
+type jsonObject struct {
+	ID         uint32         `json:"id"`
+	Name       string         `json:"name"`
+	Type       string         `json:"type"`
+	Class      string         `json:"class"`
+	X          float64        `json:"x"`
+	Y          float64        `json:"y"`
+	Width      float64        `json:"width"`
+	Height     float64        `json:"height"`
+	Rotation   float64        `json:"rotation"`
+	GID        uint32         `json:"gid"`
+	Visible    bool           `json:"visible"`
+	Properties jsonProperties `json:"properties"`
+	Ellipse    bool           `json:"ellipse"`
+	Point      bool           `json:"point"`
+	Polygon    []*Point       `json:"polygon"`
+	Polyline   []*Point       `json:"polyline"`
+	Text       *jsonText      `json:"text"`
+	Template   string         `json:"template"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (o *jsonObject) UnmarshalJSON(data []byte) error {
+	type aliasJSONObject jsonObject
+	item := aliasJSONObject{
+		Visible: true,
+	}
+
+	if err := json.Unmarshal(data, &item); err != nil {
+		return err
+	}
+
+	*o = (jsonObject)(item)
+	return nil
+}
+
+func (o *jsonObject) toObject() *Object {
+	item := &Object{
+		ID:             o.ID,
+		Name:           o.Name,
+		Type:           o.Type,
+		Class:          o.Class,
+		X:              o.X,
+		Y:              o.Y,
+		Width:          o.Width,
+		Height:         o.Height,
+		Rotation:       o.Rotation,
+		GID:            o.GID,
+		Visible:        o.Visible,
+		Properties:     o.Properties.toProperties(),
+		TemplateSource: o.Template,
+	}
+
+	if o.Ellipse {
+		item.Ellipses = []*Ellipse{{}}
+	}
+	if o.Point {
+		item.PointMarkers = []*PointMarker{{}}
+	}
+	if o.Polygon != nil {
+		points := Points(o.Polygon)
+		item.Polygons = []*Polygon{{Points: &points}}
+	}
+	if o.Polyline != nil {
+		points := Points(o.Polyline)
+		item.PolyLines = []*PolyLine{{Points: &points}}
+	}
+	if o.Text != nil {
+		item.Text = o.Text.toText()
+	}
+
+	return item
+}
+
+// jsonText is a text object content as stored in JSON format.
+type jsonText struct {
+	Text       string    `json:"text"`
+	FontFamily string    `json:"fontfamily"`
+	PixelSize  int       `json:"pixelsize"`
+	Wrap       bool      `json:"wrap"`
+	Color      *HexColor `json:"color"`
+	Bold       bool      `json:"bold"`
+	Italic     bool      `json:"italic"`
+	Underline  bool      `json:"underline"`
+	Strikeout  bool      `json:"strikeout"`
+	Kerning    bool      `json:"kerning"`
+	HAlign     string    `json:"halign"`
+	VAlign     string    `json:"valign"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (t *jsonText) UnmarshalJSON(data []byte) error {
+	type aliasJSONText jsonText
+	defaults := aliasText{}
+	defaults.SetDefaults()
+
+	item := aliasJSONText{
+		FontFamily: defaults.FontFamily,
+		PixelSize:  defaults.Size,
+		Kerning:    defaults.Kerning,
+		HAlign:     defaults.HAlign,
+		VAlign:     defaults.VAlign,
+		Color:      defaults.Color,
+	}
+
+	if err := json.Unmarshal(data, &item); err != nil {
+		return err
+	}
+
+	*t = (jsonText)(item)
+	return nil
+}
+
+func (t *jsonText) toText() *Text {
+	return &Text{
+		Text:          t.Text,
+		FontFamily:    t.FontFamily,
+		Size:          t.PixelSize,
+		Wrap:          t.Wrap,
+		Color:         t.Color,
+		Bold:          t.Bold,
+		Italic:        t.Italic,
+		Underline:     t.Underline,
+		Strikethrough: t.Strikeout,
+		Kerning:       t.Kerning,
+		HAlign:        t.HAlign,
+		VAlign:        t.VAlign,
+	}
+}
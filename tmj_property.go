@@ -0,0 +1,89 @@
+/*
+Copyright (c) 2023 Lauris Bukšis-Haberkorns <lauris@nix.lv>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package tiled
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// jsonProperty is a custom property as stored in JSON format.
+type jsonProperty struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	// Value is stored as JSON string, number, boolean or object depending on the property type.
+	Value json.RawMessage `json:"value"`
+}
+
+type jsonProperties []*jsonProperty
+
+// toProperties converts JSON properties to the same values as they would have been read from TMX.
+func (p jsonProperties) toProperties() Properties {
+	if len(p) == 0 {
+		return nil
+	}
+
+	props := make(Properties, len(p))
+	for i, jp := range p {
+		props[i] = &Property{
+			Name:  jp.Name,
+			Type:  jp.Type,
+			Value: jsonPropertyValue(jp.Value),
+		}
+	}
+	return props
+}
+
+// jsonPropertyValue returns property value in the same string form as used by TMX format.
+func jsonPropertyValue(raw json.RawMessage) string {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) > 0 && raw[0] == '"' {
+		var s string
+		if err := json.Unmarshal(raw, &s); err == nil {
+			return s
+		}
+	}
+	if bytes.Equal(raw, []byte("null")) {
+		return ""
+	}
+	return string(raw)
+}
+
+// jsonVersion is a format version that older Tiled versions stored as a number.
+type jsonVersion string
+
+// UnmarshalJSON implements json.Unmarshaler
+func (v *jsonVersion) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*v = jsonVersion(s)
+		return nil
+	}
+
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return err
+	}
+	*v = jsonVersion(n.String())
+	return nil
+}
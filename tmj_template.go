@@ -0,0 +1,51 @@
+/*
+Copyright (c) 2023 Lauris Bukšis-Haberkorns <lauris@nix.lv>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package tiled
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// jsonTemplate is an object template as stored in JSON format (.tj).
+type jsonTemplate struct {
+	Tileset *jsonTileset `json:"tileset"`
+	Object  *jsonObject  `json:"object"`
+}
+
+// decodeJSONTemplate decodes object template in JSON format.
+func decodeJSONTemplate(r io.Reader) (*Template, error) {
+	item := jsonTemplate{}
+	if err := json.NewDecoder(r).Decode(&item); err != nil {
+		return nil, err
+	}
+
+	t := &Template{}
+	if item.Tileset != nil {
+		t.Tileset = item.Tileset.toTileset()
+	}
+	if item.Object != nil {
+		t.Object = item.Object.toObject()
+	}
+	return t, nil
+}
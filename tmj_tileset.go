@@ -0,0 +1,306 @@
+/*
+Copyright (c) 2023 Lauris Bukšis-Haberkorns <lauris@nix.lv>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package tiled
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// decodeJSON decodes external tileset in JSON format (.tsj) into ts. First GID
+// and source are kept as they are map specific.
+func (ts *Tileset) decodeJSON(r io.Reader) error {
+	item := jsonTileset{}
+	if err := json.NewDecoder(r).Decode(&item); err != nil {
+		return err
+	}
+
+	tileset := item.toTileset()
+	tileset.FirstGID = ts.FirstGID
+	tileset.Source = ts.Source
+
+	*ts = *tileset
+	return nil
+}
+
+// jsonTileset is a tileset as stored in JSON format, either embedded in a map
+// or in an external tileset file (.tsj).
+type jsonTileset struct {
+	FirstGID         uint32               `json:"firstgid"`
+	Source           string               `json:"source"`
+	Version          jsonVersion          `json:"version"`
+	TiledVersion     string               `json:"tiledversion"`
+	Name             string               `json:"name"`
+	Class            string               `json:"class"`
+	TileWidth        int                  `json:"tilewidth"`
+	TileHeight       int                  `json:"tileheight"`
+	Spacing          int                  `json:"spacing"`
+	Margin           int                  `json:"margin"`
+	TileCount        int                  `json:"tilecount"`
+	Columns          int                  `json:"columns"`
+	ObjectAlignment  ObjectAlignment      `json:"objectalignment"`
+	TileRenderSize   TileRenderSize       `json:"tilerendersize"`
+	FillMode         FillMode             `json:"fillmode"`
+	Image            string               `json:"image"`
+	ImageWidth       int                  `json:"imagewidth"`
+	ImageHeight      int                  `json:"imageheight"`
+	TransparentColor *HexColor            `json:"transparentcolor"`
+	TileOffset       *jsonTileOffset      `json:"tileoffset"`
+	Grid             *jsonGrid            `json:"grid"`
+	Transformations  *jsonTransformations `json:"transformations"`
+	Properties       jsonProperties       `json:"properties"`
+	Terrains         []*jsonTerrain       `json:"terrains"`
+	Tiles            []*jsonTilesetTile   `json:"tiles"`
+	WangSets         []*jsonWangSet       `json:"wangsets"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (ts *jsonTileset) UnmarshalJSON(data []byte) error {
+	type aliasJSONTileset jsonTileset
+	defaults := aliasTileset{}
+	defaults.SetDefaults()
+
+	item := aliasJSONTileset{
+		ObjectAlignment: defaults.ObjectAlignment,
+		TileRenderSize:  defaults.TileRenderSize,
+		FillMode:        defaults.FillMode,
+	}
+
+	if err := json.Unmarshal(data, &item); err != nil {
+		return err
+	}
+
+	*ts = (jsonTileset)(item)
+	return nil
+}
+
+func (ts *jsonTileset) toTileset() *Tileset {
+	item := &Tileset{
+		Version:         string(ts.Version),
+		TiledVersion:    ts.TiledVersion,
+		FirstGID:        ts.FirstGID,
+		Source:          ts.Source,
+		Name:            ts.Name,
+		Class:           ts.Class,
+		TileWidth:       ts.TileWidth,
+		TileHeight:      ts.TileHeight,
+		Spacing:         ts.Spacing,
+		Margin:          ts.Margin,
+		TileCount:       ts.TileCount,
+		Columns:         ts.Columns,
+		ObjectAlignment: ts.ObjectAlignment,
+		TileRenderSize:  ts.TileRenderSize,
+		FillMode:        ts.FillMode,
+		Properties:      ts.Properties.toProperties(),
+		Image:           jsonImage(ts.Image, ts.ImageWidth, ts.ImageHeight, ts.TransparentColor),
+	}
+
+	if ts.TileOffset != nil {
+		item.TileOffset = &TilesetTileOffset{
+			X: ts.TileOffset.X,
+			Y: ts.TileOffset.Y,
+		}
+	}
+
+	if ts.Grid != nil {
+		item.Grid = &TilesetGrid{
+			Orientation: ts.Grid.Orientation,
+			Width:       ts.Grid.Width,
+			Height:      ts.Grid.Height,
+		}
+	}
+
+	if ts.Transformations != nil {
+		item.Transformations = &TilesetTransformations{
+			HFlip:               ts.Transformations.HFlip,
+			VFlip:               ts.Transformations.VFlip,
+			Rotate:              ts.Transformations.Rotate,
+			PreferUntransformed: ts.Transformations.PreferUntransformed,
+		}
+	}
+
+	for _, t := range ts.Terrains {
+		item.TerrainTypes = append(item.TerrainTypes, &Terrain{
+			Name:       t.Name,
+			Tile:       t.Tile,
+			Properties: t.Properties.toProperties(),
+		})
+	}
+
+	for _, t := range ts.Tiles {
+		item.Tiles = append(item.Tiles, t.toTilesetTile())
+	}
+
+	for _, w := range ts.WangSets {
+		item.WangSets = append(item.WangSets, w.toWangSet())
+	}
+
+	return item
+}
+
+// jsonImage returns image described by the separate image attributes of JSON format,
+// or nil if there is no image.
+func jsonImage(source string, width, height int, trans *HexColor) *Image {
+	if len(source) == 0 {
+		return nil
+	}
+	return &Image{
+		Source: source,
+		Trans:  trans,
+		Width:  width,
+		Height: height,
+	}
+}
+
+type jsonTileOffset struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+type jsonGrid struct {
+	Orientation string `json:"orientation"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+}
+
+type jsonTransformations struct {
+	HFlip               bool `json:"hflip"`
+	VFlip               bool `json:"vflip"`
+	Rotate              bool `json:"rotate"`
+	PreferUntransformed bool `json:"preferuntransformed"`
+}
+
+type jsonTerrain struct {
+	Name       string         `json:"name"`
+	Tile       uint32         `json:"tile"`
+	Properties jsonProperties `json:"properties"`
+}
+
+type jsonTilesetTile struct {
+	ID          uint32            `json:"id"`
+	Type        string            `json:"type"`
+	Class       string            `json:"class"`
+	Terrain     []int             `json:"terrain"`
+	Probability float32           `json:"probability"`
+	X           int               `json:"x"`
+	Y           int               `json:"y"`
+	Width       int               `json:"width"`
+	Height      int               `json:"height"`
+	Properties  jsonProperties    `json:"properties"`
+	Image       string            `json:"image"`
+	ImageWidth  int               `json:"imagewidth"`
+	ImageHeight int               `json:"imageheight"`
+	ObjectGroup *jsonLayer        `json:"objectgroup"`
+	Animation   []*AnimationFrame `json:"animation"`
+}
+
+func (t *jsonTilesetTile) toTilesetTile() *TilesetTile {
+	item := &TilesetTile{
+		ID:          t.ID,
+		Type:        t.Type,
+		Class:       t.Class,
+		Terrain:     joinIndexes(t.Terrain),
+		Probability: t.Probability,
+		X:           t.X,
+		Y:           t.Y,
+		Width:       t.Width,
+		Height:      t.Height,
+		Properties:  t.Properties.toProperties(),
+		Image:       jsonImage(t.Image, t.ImageWidth, t.ImageHeight, nil),
+		Animation:   t.Animation,
+	}
+
+	if t.ObjectGroup != nil {
+		item.ObjectGroups = []*ObjectGroup{t.ObjectGroup.toObjectGroup()}
+	}
+
+	return item
+}
+
+type jsonWangSet struct {
+	Name      string           `json:"name"`
+	Class     string           `json:"class"`
+	Type      string           `json:"type"`
+	Tile      int64            `json:"tile"`
+	Colors    []*jsonWangColor `json:"colors"`
+	WangTiles []*jsonWangTile  `json:"wangtiles"`
+}
+
+func (w *jsonWangSet) toWangSet() *WangSet {
+	item := &WangSet{
+		Name:   w.Name,
+		Class:  w.Class,
+		Type:   w.Type,
+		TileID: w.Tile,
+	}
+
+	for _, c := range w.Colors {
+		item.WangColors = append(item.WangColors, &WangColor{
+			Name:        c.Name,
+			Class:       c.Class,
+			Color:       c.Color,
+			TileID:      c.Tile,
+			Probability: c.Probability,
+		})
+	}
+
+	for _, t := range w.WangTiles {
+		item.WangTiles = append(item.WangTiles, &WangTile{
+			TileID: t.TileID,
+			WangID: joinIndexes(t.WangID),
+		})
+	}
+
+	return item
+}
+
+type jsonWangColor struct {
+	Name        string  `json:"name"`
+	Class       string  `json:"class"`
+	Color       string  `json:"color"`
+	Tile        int64   `json:"tile"`
+	Probability float32 `json:"probability"`
+}
+
+type jsonWangTile struct {
+	TileID uint32 `json:"tileid"`
+	WangID []int  `json:"wangid"`
+}
+
+// joinIndexes converts list of indexes to comma separated form used by TMX format,
+// where negative indexes are left empty.
+func joinIndexes(indexes []int) string {
+	if indexes == nil {
+		return ""
+	}
+
+	s := make([]string, len(indexes))
+	for i, idx := range indexes {
+		if idx >= 0 {
+			s[i] = strconv.Itoa(idx)
+		}
+	}
+	return strings.Join(s, ",")
+}
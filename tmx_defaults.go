@@ -42,28 +42,36 @@ type aliasMap Map
 type aliasObject Object
 type aliasObjectGroup ObjectGroup
 type aliasText Text
+type aliasTileset Tileset
 
 // SetDefaults provides default values for Group.
 func (a *aliasGroup) SetDefaults() {
 	a.Opacity = 1
 	a.Visible = true
+	a.ParallaxX = 1
+	a.ParallaxY = 1
 }
 
 // SetDefaults provides default values for ImageLayer.
 func (a *aliasImageLayer) SetDefaults() {
 	a.Opacity = 1
 	a.Visible = true
+	a.ParallaxX = 1
+	a.ParallaxY = 1
 }
 
 // SetDefaults provides default values for Layer.
 func (a *aliasLayer) SetDefaults() {
 	a.internalLayer.Opacity = 1
 	a.internalLayer.Visible = true
+	a.internalLayer.ParallaxX = 1
+	a.internalLayer.ParallaxY = 1
 }
 
 // SetDefaults provides default values for Map.
 func (a *aliasMap) SetDefaults() {
 	a.RenderOrder = "right-down"
+	a.CompressionLevel = -1
 }
 
 // SetDefaults provides default values for Object.
@@ -75,6 +83,8 @@ func (a *aliasObject) SetDefaults() {
 func (a *aliasObjectGroup) SetDefaults() {
 	a.Visible = true
 	a.Opacity = 1
+	a.ParallaxX = 1
+	a.ParallaxY = 1
 }
 
 // SetDefaults provides default values for Text.
@@ -86,3 +96,10 @@ func (a *aliasText) SetDefaults() {
 	a.VAlign = "top"
 	a.Color = &HexColor{}
 }
+
+// SetDefaults provides default values for Tileset.
+func (a *aliasTileset) SetDefaults() {
+	a.ObjectAlignment = ObjectAlignmentUnspecified
+	a.TileRenderSize = TileRenderSizeTile
+	a.FillMode = FillModeStretch
+}
@@ -0,0 +1,1011 @@
+/*
+Copyright (c) 2023 Lauris Bukšis-Haberkorns <lauris@nix.lv>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package tiled
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"image"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/lafriks/go-tiled/internal/utils"
+)
+
+// EncoderOption is used to configure how maps are written in TMX format
+type EncoderOption func(*encoder)
+
+// WithLayerEncoding overrides the encoding ("csv", "base64" or empty for XML) and
+// compression ("gzip", "zlib" or empty) of all tile layers. By default each layer
+// is written using the encoding it was loaded with.
+func WithLayerEncoding(encoding, compression string) EncoderOption {
+	return func(e *encoder) {
+		e.override = true
+		e.encoding = encoding
+		e.compression = compression
+	}
+}
+
+type encoder struct {
+	override    bool
+	encoding    string
+	compression string
+}
+
+func newEncoder(options ...EncoderOption) *encoder {
+	e := &encoder{}
+	for _, o := range options {
+		o(e)
+	}
+	return e
+}
+
+// Encode writes map in TMX format to w. Layer data is encoded from the
+// decoded Layer.Tiles. Tiles of infinite maps are stored in the chunks of the
+// layer, tiles outside of them are stored in new chunks.
+func (m *Map) Encode(w io.Writer, options ...EncoderOption) error {
+	item, err := newEncoder(options...).encodeMap(m)
+	if err != nil {
+		return err
+	}
+	return writeXML(w, item)
+}
+
+// SaveFile writes map in TMX format to the specified file. Tileset and template
+// references are written as is, so they must be valid relative to the new file.
+func (m *Map) SaveFile(fileName string, options ...EncoderOption) error {
+	return saveFile(fileName, func(w io.Writer) error {
+		return m.Encode(w, options...)
+	})
+}
+
+// Encode writes tileset in TSX format to w. Map specific FirstGID and Source
+// are not written.
+func (ts *Tileset) Encode(w io.Writer) error {
+	return writeXML(w, encodeTileset(ts, false))
+}
+
+// SaveTSX writes tileset in TSX format to the specified file. Image sources are
+// written as is, so they must be valid relative to the new file.
+func (ts *Tileset) SaveTSX(fileName string) error {
+	return saveFile(fileName, ts.Encode)
+}
+
+func saveFile(fileName string, encode func(w io.Writer) error) error {
+	f, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	if err := encode(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func writeXML(w io.Writer, v interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", " ")
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+type xmlMap struct {
+	XMLName          xml.Name         `xml:"map"`
+	Version          string           `xml:"version,attr,omitempty"`
+	TiledVersion     string           `xml:"tiledversion,attr,omitempty"`
+	Class            string           `xml:"class,attr,omitempty"`
+	Orientation      string           `xml:"orientation,attr"`
+	RenderOrder      string           `xml:"renderorder,attr,omitempty"`
+	Width            int              `xml:"width,attr"`
+	Height           int              `xml:"height,attr"`
+	TileWidth        int              `xml:"tilewidth,attr"`
+	TileHeight       int              `xml:"tileheight,attr"`
+	HexSideLength    int              `xml:"hexsidelength,attr,omitempty"`
+	StaggerAxis      Axis             `xml:"staggeraxis,attr,omitempty"`
+	StaggerIndex     StaggerIndexType `xml:"staggerindex,attr,omitempty"`
+	BackgroundColor  *HexColor        `xml:"backgroundcolor,attr,omitempty"`
+	ParallaxOriginX  string           `xml:"parallaxoriginx,attr,omitempty"`
+	ParallaxOriginY  string           `xml:"parallaxoriginy,attr,omitempty"`
+	Infinite         string           `xml:"infinite,attr"`
+	CompressionLevel string           `xml:"compressionlevel,attr,omitempty"`
+	NextLayerID      uint32           `xml:"nextlayerid,attr,omitempty"`
+	NextObjectID     uint32           `xml:"nextobjectid,attr,omitempty"`
+	Properties       *xmlProperties   `xml:"properties,omitempty"`
+	Tilesets         []*xmlTileset    `xml:"tileset"`
+	Layers           []interface{}
+}
+
+type xmlProperties struct {
+	Properties []*xmlProperty `xml:"property"`
+}
+
+type xmlProperty struct {
+	Name  string `xml:"name,attr"`
+	Type  string `xml:"type,attr,omitempty"`
+	Value string `xml:"value,attr"`
+}
+
+type xmlTileset struct {
+	XMLName         xml.Name            `xml:"tileset"`
+	Version         string              `xml:"version,attr,omitempty"`
+	TiledVersion    string              `xml:"tiledversion,attr,omitempty"`
+	FirstGID        uint32              `xml:"firstgid,attr,omitempty"`
+	Source          string              `xml:"source,attr,omitempty"`
+	Name            string              `xml:"name,attr,omitempty"`
+	Class           string              `xml:"class,attr,omitempty"`
+	TileWidth       int                 `xml:"tilewidth,attr,omitempty"`
+	TileHeight      int                 `xml:"tileheight,attr,omitempty"`
+	Spacing         int                 `xml:"spacing,attr,omitempty"`
+	Margin          int                 `xml:"margin,attr,omitempty"`
+	TileCount       int                 `xml:"tilecount,attr,omitempty"`
+	Columns         *int                `xml:"columns,attr"`
+	ObjectAlignment ObjectAlignment     `xml:"objectalignment,attr,omitempty"`
+	TileRenderSize  TileRenderSize      `xml:"tilerendersize,attr,omitempty"`
+	FillMode        FillMode            `xml:"fillmode,attr,omitempty"`
+	TileOffset      *TilesetTileOffset  `xml:"tileoffset"`
+	Grid            *TilesetGrid        `xml:"grid"`
+	Transformations *xmlTransformations `xml:"transformations"`
+	Properties      *xmlProperties      `xml:"properties,omitempty"`
+	Image           *xmlImage           `xml:"image"`
+	TerrainTypes    *xmlTerrainTypes    `xml:"terraintypes"`
+	Tiles           []*xmlTilesetTile   `xml:"tile"`
+	WangSets        *xmlWangSets        `xml:"wangsets"`
+}
+
+type xmlTransformations struct {
+	HFlip               string `xml:"hflip,attr"`
+	VFlip               string `xml:"vflip,attr"`
+	Rotate              string `xml:"rotate,attr"`
+	PreferUntransformed string `xml:"preferuntransformed,attr"`
+}
+
+type xmlImage struct {
+	Format string   `xml:"format,attr,omitempty"`
+	Source string   `xml:"source,attr,omitempty"`
+	Trans  string   `xml:"trans,attr,omitempty"`
+	Width  int      `xml:"width,attr,omitempty"`
+	Height int      `xml:"height,attr,omitempty"`
+	Data   *xmlData `xml:"data"`
+}
+
+type xmlTerrainTypes struct {
+	Terrains []*xmlTerrain `xml:"terrain"`
+}
+
+type xmlTerrain struct {
+	Name       string         `xml:"name,attr"`
+	Tile       uint32         `xml:"tile,attr"`
+	Properties *xmlProperties `xml:"properties,omitempty"`
+}
+
+type xmlTilesetTile struct {
+	ID           uint32            `xml:"id,attr"`
+	Type         string            `xml:"type,attr,omitempty"`
+	Class        string            `xml:"class,attr,omitempty"`
+	Terrain      string            `xml:"terrain,attr,omitempty"`
+	Probability  string            `xml:"probability,attr,omitempty"`
+	X            int               `xml:"x,attr,omitempty"`
+	Y            int               `xml:"y,attr,omitempty"`
+	Width        int               `xml:"width,attr,omitempty"`
+	Height       int               `xml:"height,attr,omitempty"`
+	Properties   *xmlProperties    `xml:"properties,omitempty"`
+	Image        *xmlImage         `xml:"image"`
+	ObjectGroups []*xmlObjectGroup `xml:"objectgroup"`
+	Animation    *xmlAnimation     `xml:"animation"`
+}
+
+type xmlAnimation struct {
+	Frames []*AnimationFrame `xml:"frame"`
+}
+
+type xmlWangSets struct {
+	WangSets []*xmlWangSet `xml:"wangset"`
+}
+
+type xmlWangSet struct {
+	Name       string          `xml:"name,attr"`
+	Class      string          `xml:"class,attr,omitempty"`
+	Type       string          `xml:"type,attr,omitempty"`
+	TileID     int64           `xml:"tile,attr"`
+	WangColors []*xmlWangColor `xml:"wangcolor"`
+	WangTiles  []*WangTile     `xml:"wangtile"`
+}
+
+type xmlWangColor struct {
+	Name        string `xml:"name,attr"`
+	Class       string `xml:"class,attr,omitempty"`
+	Color       string `xml:"color,attr"`
+	TileID      int64  `xml:"tile,attr"`
+	Probability string `xml:"probability,attr"`
+}
+
+type xmlLayer struct {
+	XMLName    xml.Name       `xml:"layer"`
+	ID         uint32         `xml:"id,attr,omitempty"`
+	Name       string         `xml:"name,attr"`
+	Class      string         `xml:"class,attr,omitempty"`
+	Width      int            `xml:"width,attr"`
+	Height     int            `xml:"height,attr"`
+	Opacity    string         `xml:"opacity,attr,omitempty"`
+	Visible    string         `xml:"visible,attr,omitempty"`
+	OffsetX    int            `xml:"offsetx,attr,omitempty"`
+	OffsetY    int            `xml:"offsety,attr,omitempty"`
+	ParallaxX  string         `xml:"parallaxx,attr,omitempty"`
+	ParallaxY  string         `xml:"parallaxy,attr,omitempty"`
+	TintColor  *HexColor      `xml:"tintcolor,attr,omitempty"`
+	Properties *xmlProperties `xml:"properties,omitempty"`
+	Data       *xmlData       `xml:"data"`
+}
+
+type xmlData struct {
+	Encoding    string         `xml:"encoding,attr,omitempty"`
+	Compression string         `xml:"compression,attr,omitempty"`
+	Text        string         `xml:",innerxml"`
+	Tiles       []*xmlDataTile `xml:"tile"`
+	Chunks      []*xmlChunk    `xml:"chunk"`
+}
+
+type xmlDataTile struct {
+	GID uint32 `xml:"gid,attr,omitempty"`
+}
+
+type xmlChunk struct {
+	X      int    `xml:"x,attr"`
+	Y      int    `xml:"y,attr"`
+	Width  int    `xml:"width,attr"`
+	Height int    `xml:"height,attr"`
+	Text   string `xml:",innerxml"`
+}
+
+type xmlObjectGroup struct {
+	XMLName    xml.Name       `xml:"objectgroup"`
+	ID         uint32         `xml:"id,attr,omitempty"`
+	Name       string         `xml:"name,attr,omitempty"`
+	Class      string         `xml:"class,attr,omitempty"`
+	Color      *HexColor      `xml:"color,attr,omitempty"`
+	Opacity    string         `xml:"opacity,attr,omitempty"`
+	Visible    string         `xml:"visible,attr,omitempty"`
+	OffsetX    int            `xml:"offsetx,attr,omitempty"`
+	OffsetY    int            `xml:"offsety,attr,omitempty"`
+	ParallaxX  string         `xml:"parallaxx,attr,omitempty"`
+	ParallaxY  string         `xml:"parallaxy,attr,omitempty"`
+	TintColor  *HexColor      `xml:"tintcolor,attr,omitempty"`
+	DrawOrder  string         `xml:"draworder,attr,omitempty"`
+	Properties *xmlProperties `xml:"properties,omitempty"`
+	Objects    []*xmlObject   `xml:"object"`
+}
+
+type xmlObject struct {
+	ID         uint32         `xml:"id,attr,omitempty"`
+	Template   string         `xml:"template,attr,omitempty"`
+	Name       string         `xml:"name,attr,omitempty"`
+	Type       string         `xml:"type,attr,omitempty"`
+	Class      string         `xml:"class,attr,omitempty"`
+	GID        uint32         `xml:"gid,attr,omitempty"`
+	X          string         `xml:"x,attr,omitempty"`
+	Y          string         `xml:"y,attr,omitempty"`
+	Width      string         `xml:"width,attr,omitempty"`
+	Height     string         `xml:"height,attr,omitempty"`
+	Rotation   string         `xml:"rotation,attr,omitempty"`
+	Visible    string         `xml:"visible,attr,omitempty"`
+	Properties *xmlProperties `xml:"properties,omitempty"`
+	Ellipses   []struct{}     `xml:"ellipse"`
+	Points     []struct{}     `xml:"point"`
+	Polygons   []*xmlPoints   `xml:"polygon"`
+	PolyLines  []*xmlPoints   `xml:"polyline"`
+	Text       *xmlText       `xml:"text"`
+}
+
+type xmlPoints struct {
+	Points string `xml:"points,attr"`
+}
+
+type xmlText struct {
+	FontFamily    string    `xml:"fontfamily,attr,omitempty"`
+	Size          int       `xml:"pixelsize,attr,omitempty"`
+	Wrap          string    `xml:"wrap,attr,omitempty"`
+	Color         *HexColor `xml:"color,attr,omitempty"`
+	Bold          string    `xml:"bold,attr,omitempty"`
+	Italic        string    `xml:"italic,attr,omitempty"`
+	Underline     string    `xml:"underline,attr,omitempty"`
+	Strikethrough string    `xml:"strikeout,attr,omitempty"`
+	Kerning       string    `xml:"kerning,attr,omitempty"`
+	HAlign        string    `xml:"halign,attr,omitempty"`
+	VAlign        string    `xml:"valign,attr,omitempty"`
+	Text          string    `xml:",chardata"`
+}
+
+type xmlImageLayer struct {
+	XMLName    xml.Name       `xml:"imagelayer"`
+	ID         uint32         `xml:"id,attr,omitempty"`
+	Name       string         `xml:"name,attr"`
+	Class      string         `xml:"class,attr,omitempty"`
+	OffsetX    int            `xml:"offsetx,attr,omitempty"`
+	OffsetY    int            `xml:"offsety,attr,omitempty"`
+	ParallaxX  string         `xml:"parallaxx,attr,omitempty"`
+	ParallaxY  string         `xml:"parallaxy,attr,omitempty"`
+	TintColor  *HexColor      `xml:"tintcolor,attr,omitempty"`
+	X          int            `xml:"x,attr,omitempty"`
+	Y          int            `xml:"y,attr,omitempty"`
+	Opacity    string         `xml:"opacity,attr,omitempty"`
+	Visible    string         `xml:"visible,attr,omitempty"`
+	RepeatX    string         `xml:"repeatx,attr,omitempty"`
+	RepeatY    string         `xml:"repeaty,attr,omitempty"`
+	Properties *xmlProperties `xml:"properties,omitempty"`
+	Image      *xmlImage      `xml:"image"`
+}
+
+type xmlGroup struct {
+	XMLName    xml.Name       `xml:"group"`
+	ID         uint32         `xml:"id,attr,omitempty"`
+	Name       string         `xml:"name,attr"`
+	Class      string         `xml:"class,attr,omitempty"`
+	OffsetX    int            `xml:"offsetx,attr,omitempty"`
+	OffsetY    int            `xml:"offsety,attr,omitempty"`
+	ParallaxX  string         `xml:"parallaxx,attr,omitempty"`
+	ParallaxY  string         `xml:"parallaxy,attr,omitempty"`
+	TintColor  *HexColor      `xml:"tintcolor,attr,omitempty"`
+	Opacity    string         `xml:"opacity,attr,omitempty"`
+	Visible    string         `xml:"visible,attr,omitempty"`
+	Properties *xmlProperties `xml:"properties,omitempty"`
+	Layers     []interface{}
+}
+
+func (e *encoder) encodeMap(m *Map) (*xmlMap, error) {
+	width, height := m.encodedSize()
+	item := &xmlMap{
+		Version:         m.Version,
+		TiledVersion:    m.TiledVersion,
+		Class:           m.Class,
+		Orientation:     m.Orientation,
+		RenderOrder:     m.RenderOrder,
+		Width:           width,
+		Height:          height,
+		TileWidth:       m.TileWidth,
+		TileHeight:      m.TileHeight,
+		HexSideLength:   m.HexSideLength,
+		StaggerAxis:     m.StaggerAxis,
+		StaggerIndex:    m.StaggerIndex,
+		BackgroundColor: m.BackgroundColor,
+		ParallaxOriginX: formatOptionalFloat(m.ParallaxOriginX),
+		ParallaxOriginY: formatOptionalFloat(m.ParallaxOriginY),
+		Infinite:        "0",
+		NextLayerID:     m.NextLayerID,
+		NextObjectID:    m.NextObjectID,
+	}
+	if m.IsInfinite {
+		item.Infinite = "1"
+	}
+	if m.CompressionLevel > 0 {
+		item.CompressionLevel = strconv.Itoa(m.CompressionLevel)
+	}
+	if m.Properties != nil {
+		item.Properties = encodeProperties(*m.Properties)
+	}
+	for _, ts := range m.Tilesets {
+		item.Tilesets = append(item.Tilesets, encodeTileset(ts, true))
+	}
+
+	var err error
+	item.Layers, err = e.encodeLayers(m, joinMapLayers(m.Children, m.Layers, m.ObjectGroups, m.ImageLayers, m.Groups))
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+func (e *encoder) encodeLayers(m *Map, layers MapLayers) ([]interface{}, error) {
+	items := make([]interface{}, 0, len(layers))
+	for _, l := range layers {
+		var item interface{}
+		var err error
+		switch l := l.(type) {
+		case *Layer:
+			item, err = e.encodeLayer(m, l)
+		case *ObjectGroup:
+			item = encodeObjectGroup(l)
+		case *ImageLayer:
+			item = encodeImageLayer(l)
+		case *Group:
+			item, err = e.encodeGroup(m, l)
+		}
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (e *encoder) encodeLayer(m *Map, l *Layer) (*xmlLayer, error) {
+	width, height := m.encodedSize()
+	item := &xmlLayer{
+		ID:         l.ID,
+		Name:       l.Name,
+		Class:      l.Class,
+		Width:      width,
+		Height:     height,
+		Opacity:    formatOpacity(l.Opacity),
+		Visible:    formatVisible(l.Visible),
+		OffsetX:    l.OffsetX,
+		OffsetY:    l.OffsetY,
+		ParallaxX:  formatParallax(l.ParallaxX),
+		ParallaxY:  formatParallax(l.ParallaxY),
+		TintColor:  l.TintColor,
+		Properties: encodeProperties(l.Properties),
+	}
+
+	encoding, compression := l.Encoding, l.Compression
+	if e.override {
+		encoding, compression = e.encoding, e.compression
+	}
+	if encoding == "" {
+		// Compression is only applicable to base64 encoded data
+		compression = ""
+	}
+
+	data := &xmlData{
+		Encoding:    encoding,
+		Compression: compression,
+	}
+	item.Data = data
+
+	if m.IsInfinite {
+		if encoding == "" {
+			// Chunks can only be stored using CSV or base64 encoding
+			data.Encoding = "csv"
+		}
+		for _, chunk := range infiniteLayerChunks(m, l) {
+			gids, empty := infiniteLayerGIDs(m, l, chunk)
+			if empty {
+				continue
+			}
+			text, err := encodeGIDs(gids, chunk.Dx(), data.Encoding, data.Compression)
+			if err != nil {
+				return nil, err
+			}
+			data.Chunks = append(data.Chunks, &xmlChunk{
+				X:      chunk.Min.X,
+				Y:      chunk.Min.Y,
+				Width:  chunk.Dx(),
+				Height: chunk.Dy(),
+				Text:   text,
+			})
+		}
+		return item, nil
+	}
+
+	gids := make([]uint32, len(l.Tiles))
+	for i, t := range l.Tiles {
+		gids[i] = layerTileGID(t)
+	}
+	if encoding == "" {
+		data.Tiles = make([]*xmlDataTile, len(gids))
+		for i, gid := range gids {
+			data.Tiles[i] = &xmlDataTile{GID: gid}
+		}
+		return item, nil
+	}
+
+	var err error
+	data.Text, err = encodeGIDs(gids, m.Width, encoding, compression)
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// encodedSize returns the map size written to the file. Size of infinite maps is replaced by the size
+// of their chunks when they are loaded, so the size stored in the loaded file is written instead.
+func (m *Map) encodedSize() (width, height int) {
+	if m.IsInfinite && m.declaredWidth > 0 && m.declaredHeight > 0 {
+		return m.declaredWidth, m.declaredHeight
+	}
+	return m.Width, m.Height
+}
+
+// infiniteChunkSize is the size of chunks created by Tiled
+const infiniteChunkSize = 16
+
+// infiniteLayerChunks returns rectangles in map tile coordinates of the chunks the tiles of an infinite map
+// layer are stored in. Chunks of the loaded layer are kept, tiles outside of them are stored in new chunks
+// aligned the same way as in Tiled.
+func infiniteLayerChunks(m *Map, l *Layer) []image.Rectangle {
+	chunks := make([]image.Rectangle, 0, len(l.Chunks))
+	for _, c := range l.Chunks {
+		chunks = append(chunks, image.Rect(c.X, c.Y, c.X+c.Width, c.Y+c.Height))
+	}
+
+	origin := infiniteLayerOrigin(m)
+	for i, t := range l.Tiles {
+		if layerTileGID(t) == 0 {
+			continue
+		}
+		p := image.Pt(origin.X+i%m.Width, origin.Y+i/m.Width)
+		covered := false
+		for _, c := range chunks {
+			if p.In(c) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			min := image.Pt(utils.FloorDiv(p.X, infiniteChunkSize), utils.FloorDiv(p.Y, infiniteChunkSize)).Mul(infiniteChunkSize)
+			chunks = append(chunks, image.Rectangle{Min: min, Max: min.Add(image.Pt(infiniteChunkSize, infiniteChunkSize))})
+		}
+	}
+	return chunks
+}
+
+// infiniteLayerGIDs returns GIDs of the tiles of an infinite map layer within the chunk, and whether all of them are empty.
+func infiniteLayerGIDs(m *Map, l *Layer, chunk image.Rectangle) (gids []uint32, empty bool) {
+	origin := infiniteLayerOrigin(m)
+	gids = make([]uint32, 0, chunk.Dx()*chunk.Dy())
+	empty = true
+	for y := chunk.Min.Y; y < chunk.Max.Y; y++ {
+		for x := chunk.Min.X; x < chunk.Max.X; x++ {
+			var gid uint32
+			tx, ty := x-origin.X, y-origin.Y
+			if tx >= 0 && tx < m.Width && ty >= 0 && ty < m.Height && ty*m.Width+tx < len(l.Tiles) {
+				gid = layerTileGID(l.Tiles[ty*m.Width+tx])
+			}
+			if gid != 0 {
+				empty = false
+			}
+			gids = append(gids, gid)
+		}
+	}
+	return gids, empty
+}
+
+// infiniteLayerOrigin returns map tile coordinates of the first layer tile of an infinite map.
+func infiniteLayerOrigin(m *Map) image.Point {
+	if m.Border == nil {
+		return image.Point{}
+	}
+	return image.Pt(m.Border.MinX, m.Border.MinY)
+}
+
+func (e *encoder) encodeGroup(m *Map, g *Group) (*xmlGroup, error) {
+	item := &xmlGroup{
+		ID:         g.ID,
+		Name:       g.Name,
+		Class:      g.Class,
+		OffsetX:    g.OffsetX,
+		OffsetY:    g.OffsetY,
+		ParallaxX:  formatParallax(g.ParallaxX),
+		ParallaxY:  formatParallax(g.ParallaxY),
+		TintColor:  g.TintColor,
+		Opacity:    formatOpacity(g.Opacity),
+		Visible:    formatVisible(g.Visible),
+		Properties: encodeProperties(g.Properties),
+	}
+
+	var err error
+	item.Layers, err = e.encodeLayers(m, joinMapLayers(g.Children, g.Layers, g.ObjectGroups, g.ImageLayers, g.Groups))
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// layerTileGID returns global tile ID including flip flags of the layer tile
+func layerTileGID(t *LayerTile) uint32 {
+	if t == nil || t.Nil || t.Tileset == nil {
+		return 0
+	}
+	gid := t.Tileset.FirstGID + t.ID
+	if t.HorizontalFlip {
+		gid |= tileHorizontalFlipMask
+	}
+	if t.VerticalFlip {
+		gid |= tileVerticalFlipMask
+	}
+	if t.DiagonalFlip {
+		gid |= tileDiagonalFlipMask
+	}
+	if t.RotatedHexagonal120 {
+		gid |= tileRotatedHexagonal120Mask
+	}
+	return gid
+}
+
+// encodeGIDs encodes tile GIDs using CSV or base64 encoding
+func encodeGIDs(gids []uint32, width int, encoding, compression string) (string, error) {
+	switch encoding {
+	case "csv":
+		var sb strings.Builder
+		sb.WriteByte('\n')
+		for i, gid := range gids {
+			sb.WriteString(strconv.FormatUint(uint64(gid), 10))
+			if i == len(gids)-1 {
+				break
+			}
+			sb.WriteByte(',')
+			if width > 0 && (i+1)%width == 0 {
+				sb.WriteByte('\n')
+			}
+		}
+		sb.WriteByte('\n')
+		return sb.String(), nil
+	case "base64":
+		raw := make([]byte, len(gids)*4)
+		for i, gid := range gids {
+			binary.LittleEndian.PutUint32(raw[i*4:], gid)
+		}
+
+		var buf bytes.Buffer
+		var w io.WriteCloser
+		switch compression {
+		case "gzip":
+			w = gzip.NewWriter(&buf)
+		case "zlib":
+			w = zlib.NewWriter(&buf)
+		case "":
+			buf.Write(raw)
+		default:
+			return "", ErrUnknownCompression
+		}
+		if w != nil {
+			if _, err := w.Write(raw); err != nil {
+				return "", err
+			}
+			if err := w.Close(); err != nil {
+				return "", err
+			}
+		}
+		return "\n" + base64.StdEncoding.EncodeToString(buf.Bytes()) + "\n", nil
+	default:
+		return "", ErrUnknownEncoding
+	}
+}
+
+func encodeObjectGroup(og *ObjectGroup) *xmlObjectGroup {
+	item := &xmlObjectGroup{
+		ID:         og.ID,
+		Name:       og.Name,
+		Class:      og.Class,
+		Color:      og.Color,
+		Opacity:    formatOpacity(og.Opacity),
+		Visible:    formatVisible(og.Visible),
+		OffsetX:    og.OffsetX,
+		OffsetY:    og.OffsetY,
+		ParallaxX:  formatParallax(og.ParallaxX),
+		ParallaxY:  formatParallax(og.ParallaxY),
+		TintColor:  og.TintColor,
+		DrawOrder:  og.DrawOrder,
+		Properties: encodeProperties(og.Properties),
+	}
+	for _, o := range og.Objects {
+		item.Objects = append(item.Objects, encodeObject(o))
+	}
+	return item
+}
+
+func encodeObject(o *Object) *xmlObject {
+	item := &xmlObject{
+		ID:         o.ID,
+		Template:   o.TemplateSource,
+		Name:       o.Name,
+		Type:       o.Type,
+		Class:      o.Class,
+		GID:        o.GID,
+		X:          formatOptionalFloat(o.X),
+		Y:          formatOptionalFloat(o.Y),
+		Width:      formatOptionalFloat(o.Width),
+		Height:     formatOptionalFloat(o.Height),
+		Rotation:   formatOptionalFloat(o.Rotation),
+		Visible:    formatVisible(o.Visible),
+		Properties: encodeProperties(o.Properties),
+		Ellipses:   make([]struct{}, len(o.Ellipses)),
+		Points:     make([]struct{}, len(o.PointMarkers)),
+	}
+	for _, p := range o.Polygons {
+		item.Polygons = append(item.Polygons, encodePoints(p.Points))
+	}
+	for _, p := range o.PolyLines {
+		item.PolyLines = append(item.PolyLines, encodePoints(p.Points))
+	}
+	if t := o.Text; t != nil {
+		item.Text = &xmlText{
+			Wrap:          formatBool(t.Wrap),
+			Bold:          formatBool(t.Bold),
+			Italic:        formatBool(t.Italic),
+			Underline:     formatBool(t.Underline),
+			Strikethrough: formatBool(t.Strikethrough),
+			Text:          t.Text,
+		}
+		// Only values that differ from defaults are written
+		if t.FontFamily != "sans-serif" {
+			item.Text.FontFamily = t.FontFamily
+		}
+		if t.Size != 16 {
+			item.Text.Size = t.Size
+		}
+		if t.Color != nil && *t.Color != (HexColor{}) {
+			item.Text.Color = t.Color
+		}
+		if !t.Kerning {
+			item.Text.Kerning = "0"
+		}
+		if t.HAlign != "left" {
+			item.Text.HAlign = t.HAlign
+		}
+		if t.VAlign != "top" {
+			item.Text.VAlign = t.VAlign
+		}
+	}
+	return item
+}
+
+func encodePoints(points *Points) *xmlPoints {
+	if points == nil {
+		return &xmlPoints{}
+	}
+	s := make([]string, len(*points))
+	for i, p := range *points {
+		s[i] = formatFloat(p.X) + "," + formatFloat(p.Y)
+	}
+	return &xmlPoints{Points: strings.Join(s, " ")}
+}
+
+func encodeImageLayer(il *ImageLayer) *xmlImageLayer {
+	return &xmlImageLayer{
+		ID:         il.ID,
+		Name:       il.Name,
+		Class:      il.Class,
+		OffsetX:    il.OffsetX,
+		OffsetY:    il.OffsetY,
+		ParallaxX:  formatParallax(il.ParallaxX),
+		ParallaxY:  formatParallax(il.ParallaxY),
+		TintColor:  il.TintColor,
+		X:          il.X,
+		Y:          il.Y,
+		Opacity:    formatOpacity(il.Opacity),
+		Visible:    formatVisible(il.Visible),
+		RepeatX:    formatBool(il.RepeatX),
+		RepeatY:    formatBool(il.RepeatY),
+		Properties: encodeProperties(il.Properties),
+		Image:      encodeImage(il.Image),
+	}
+}
+
+func encodeImage(img *Image) *xmlImage {
+	if img == nil {
+		return nil
+	}
+	item := &xmlImage{
+		Format: img.Format,
+		Source: img.Source,
+		Width:  img.Width,
+		Height: img.Height,
+	}
+	if img.Trans != nil {
+		// Unlike other colors, the transparent color is stored without the leading hash
+		item.Trans = strings.TrimPrefix(img.Trans.String(), "#")
+	}
+	if img.Data != nil {
+		item.Data = &xmlData{
+			Encoding:    img.Data.Encoding,
+			Compression: img.Data.Compression,
+			Text:        string(img.Data.RawData),
+		}
+	}
+	return item
+}
+
+// encodeTileset encodes tileset either as a standalone TSX document or as a
+// map tileset. When map tileset is stored in an external file only its
+// reference is written.
+func encodeTileset(ts *Tileset, inMap bool) *xmlTileset {
+	item := &xmlTileset{}
+	if inMap {
+		item.FirstGID = ts.FirstGID
+		if len(ts.Source) > 0 {
+			item.Source = ts.Source
+			return item
+		}
+	}
+
+	columns := ts.Columns
+	item.Version = ts.Version
+	item.TiledVersion = ts.TiledVersion
+	item.Name = ts.Name
+	item.Class = ts.Class
+	item.TileWidth = ts.TileWidth
+	item.TileHeight = ts.TileHeight
+	item.Spacing = ts.Spacing
+	item.Margin = ts.Margin
+	item.TileCount = ts.TileCount
+	item.Columns = &columns
+	if ts.ObjectAlignment != ObjectAlignmentUnspecified {
+		item.ObjectAlignment = ts.ObjectAlignment
+	}
+	if ts.TileRenderSize != TileRenderSizeTile {
+		item.TileRenderSize = ts.TileRenderSize
+	}
+	if ts.FillMode != FillModeStretch {
+		item.FillMode = ts.FillMode
+	}
+	item.TileOffset = ts.TileOffset
+	item.Grid = ts.Grid
+	if tr := ts.Transformations; tr != nil {
+		item.Transformations = &xmlTransformations{
+			HFlip:               formatFlag(tr.HFlip),
+			VFlip:               formatFlag(tr.VFlip),
+			Rotate:              formatFlag(tr.Rotate),
+			PreferUntransformed: formatFlag(tr.PreferUntransformed),
+		}
+	}
+	item.Properties = encodeProperties(ts.Properties)
+	item.Image = encodeImage(ts.Image)
+
+	if len(ts.TerrainTypes) > 0 {
+		item.TerrainTypes = &xmlTerrainTypes{}
+		for _, t := range ts.TerrainTypes {
+			item.TerrainTypes.Terrains = append(item.TerrainTypes.Terrains, &xmlTerrain{
+				Name:       t.Name,
+				Tile:       t.Tile,
+				Properties: encodeProperties(t.Properties),
+			})
+		}
+	}
+
+	for _, t := range ts.Tiles {
+		tile := &xmlTilesetTile{
+			ID:          t.ID,
+			Type:        t.Type,
+			Class:       t.Class,
+			Terrain:     t.Terrain,
+			Probability: formatOptionalFloat32(t.Probability),
+			X:           t.X,
+			Y:           t.Y,
+			Width:       t.Width,
+			Height:      t.Height,
+			Properties:  encodeProperties(t.Properties),
+			Image:       encodeImage(t.Image),
+		}
+		for _, og := range t.ObjectGroups {
+			tile.ObjectGroups = append(tile.ObjectGroups, encodeObjectGroup(og))
+		}
+		if len(t.Animation) > 0 {
+			tile.Animation = &xmlAnimation{Frames: t.Animation}
+		}
+		item.Tiles = append(item.Tiles, tile)
+	}
+
+	if len(ts.WangSets) > 0 {
+		item.WangSets = &xmlWangSets{}
+		for _, ws := range ts.WangSets {
+			wangSet := &xmlWangSet{
+				Name:      ws.Name,
+				Class:     ws.Class,
+				Type:      ws.Type,
+				TileID:    ws.TileID,
+				WangTiles: ws.WangTiles,
+			}
+			for _, wc := range ws.WangColors {
+				wangSet.WangColors = append(wangSet.WangColors, &xmlWangColor{
+					Name:        wc.Name,
+					Class:       wc.Class,
+					Color:       wc.Color,
+					TileID:      wc.TileID,
+					Probability: formatFloat32(wc.Probability),
+				})
+			}
+			item.WangSets.WangSets = append(item.WangSets.WangSets, wangSet)
+		}
+	}
+
+	return item
+}
+
+func encodeProperties(props Properties) *xmlProperties {
+	if len(props) == 0 {
+		return nil
+	}
+	item := &xmlProperties{}
+	for _, p := range props {
+		item.Properties = append(item.Properties, &xmlProperty{
+			Name:  p.Name,
+			Type:  p.Type,
+			Value: p.Value,
+		})
+	}
+	return item
+}
+
+// formatBool returns "1" for true and empty string for false, so that the attribute is omitted
+func formatBool(b bool) string {
+	if b {
+		return "1"
+	}
+	return ""
+}
+
+// formatFlag returns "1" or "0" for attributes that are always written
+func formatFlag(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+// formatVisible returns "0" for hidden elements and empty string for visible ones
+func formatVisible(visible bool) string {
+	if visible {
+		return ""
+	}
+	return "0"
+}
+
+// formatOpacity returns empty string for fully opaque elements
+func formatOpacity(opacity float32) string {
+	if opacity == 1 {
+		return ""
+	}
+	return formatFloat32(opacity)
+}
+
+// formatParallax returns empty string for the default parallax factor
+func formatParallax(f float64) string {
+	if f == 1 {
+		return ""
+	}
+	return formatFloat(f)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func formatFloat32(f float32) string {
+	return strconv.FormatFloat(float64(f), 'f', -1, 32)
+}
+
+func formatOptionalFloat(f float64) string {
+	if f == 0 {
+		return ""
+	}
+	return formatFloat(f)
+}
+
+func formatOptionalFloat32(f float32) string {
+	if f == 0 {
+		return ""
+	}
+	return formatFloat32(f)
+}
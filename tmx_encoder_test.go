@@ -0,0 +1,321 @@
+/*
+Copyright (c) 2023 Lauris Bukšis-Haberkorns <lauris@nix.lv>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package tiled
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeRoundTrip(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join(GetAssetsDirectory(), "*.tm[xj]"))
+	assert.NoError(t, err)
+
+	for _, fileName := range files {
+		switch filepath.Base(fileName) {
+		case "invalid.tmx", "loader.tmx":
+			continue
+		}
+		t.Run(filepath.Base(fileName), func(t *testing.T) {
+			m1, err := LoadFile(fileName)
+			assert.NoError(t, err)
+
+			var buf1 bytes.Buffer
+			assert.NoError(t, m1.Encode(&buf1))
+
+			m2, err := LoadReader(GetAssetsDirectory(), bytes.NewReader(buf1.Bytes()))
+			assert.NoError(t, err)
+
+			var buf2 bytes.Buffer
+			assert.NoError(t, m2.Encode(&buf2))
+			assert.Equal(t, buf1.String(), buf2.String())
+
+			clearLayerData(m1.Layers, m1.Groups)
+			clearLayerData(m2.Layers, m2.Groups)
+			assert.Equal(t, m1, m2)
+		})
+	}
+}
+
+// xmlNode is an XML element with attributes and text normalized for comparison.
+type xmlNode struct {
+	Name     string
+	Attrs    map[string]string
+	Text     string
+	Children []*xmlNode
+}
+
+// readXMLTree reads XML document into a tree of elements. Whitespace is removed from the text,
+// so that documents can be compared regardless of their formatting.
+func readXMLTree(t *testing.T, r io.Reader) *xmlNode {
+	root := &xmlNode{}
+	stack := []*xmlNode{root}
+	d := xml.NewDecoder(r)
+	for {
+		token, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if !assert.NoError(t, err) {
+			return nil
+		}
+		parent := stack[len(stack)-1]
+		switch token := token.(type) {
+		case xml.StartElement:
+			node := &xmlNode{Name: token.Name.Local, Attrs: make(map[string]string)}
+			for _, a := range token.Attr {
+				node.Attrs[a.Name.Local] = a.Value
+			}
+			parent.Children = append(parent.Children, node)
+			stack = append(stack, node)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			parent.Text += strings.Join(strings.Fields(string(token)), "")
+		}
+	}
+	return root
+}
+
+// normalizeXMLTree removes attributes which are written only when they differ from their default values,
+// and replaces compressed layer data by the decompressed data, as it depends on the compressor.
+func normalizeXMLTree(t *testing.T, node *xmlNode, compression string) {
+	switch node.Name {
+	case "map":
+		if node.Attrs["compressionlevel"] == "-1" {
+			delete(node.Attrs, "compressionlevel")
+		}
+	case "object":
+		for _, name := range []string{"x", "y"} {
+			if node.Attrs[name] == "0" {
+				delete(node.Attrs, name)
+			}
+		}
+	case "data":
+		compression = node.Attrs["compression"]
+	}
+	if (node.Name == "data" || node.Name == "chunk") && compression != "" && node.Text != "" {
+		data := &Data{Compression: compression, RawData: []byte(node.Text)}
+		decoded, err := data.decodeBase64()
+		assert.NoError(t, err)
+		node.Text = string(decoded)
+	}
+	for _, child := range node.Children {
+		normalizeXMLTree(t, child, compression)
+	}
+}
+
+func TestEncodeMatchesSource(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join(GetAssetsDirectory(), "*.tmx"))
+	assert.NoError(t, err)
+
+	for _, fileName := range files {
+		switch filepath.Base(fileName) {
+		case "invalid.tmx", "loader.tmx":
+			continue
+		}
+		t.Run(filepath.Base(fileName), func(t *testing.T) {
+			m, err := LoadFile(fileName)
+			assert.NoError(t, err)
+
+			var buf bytes.Buffer
+			assert.NoError(t, m.Encode(&buf))
+
+			f, err := os.Open(fileName)
+			assert.NoError(t, err)
+			defer f.Close()
+			source := readXMLTree(t, f)
+			encoded := readXMLTree(t, &buf)
+			normalizeXMLTree(t, source, "")
+			normalizeXMLTree(t, encoded, "")
+			assert.Equal(t, source, encoded)
+		})
+	}
+}
+
+func TestEncodeInfinite(t *testing.T) {
+	m, err := LoadFile(filepath.Join(GetAssetsDirectory(), "test_infinite_negative.tmx"))
+	assert.NoError(t, err)
+
+	// Tiles edited after loading are encoded, including tiles outside of the loaded chunks
+	layer := m.Layers[0]
+	tile, err := m.TileGIDToTile(3)
+	assert.NoError(t, err)
+	outside := (3+16)*m.Width + (-5 + 16)
+	assert.Equal(t, uint32(0), layerTileGID(layer.Tiles[outside]))
+	layer.Tiles[outside] = tile
+	layer.Tiles[16*m.Width+16] = NilLayerTile
+
+	var buf bytes.Buffer
+	assert.NoError(t, m.Encode(&buf))
+	m2, err := LoadReader(GetAssetsDirectory(), &buf)
+	assert.NoError(t, err)
+
+	// Size stored in the file is kept
+	assert.Equal(t, 30, m2.declaredWidth)
+	assert.Equal(t, 20, m2.declaredHeight)
+	assert.Equal(t, m.Border, m2.Border)
+	assert.Len(t, m2.Layers[0].Chunks, 3)
+	if assert.Len(t, m2.Layers[0].Tiles, len(layer.Tiles)) {
+		for i, tile := range layer.Tiles {
+			assert.Equal(t, layerTileGID(tile), layerTileGID(m2.Layers[0].Tiles[i]), "tile %d", i)
+		}
+	}
+}
+
+func TestEncodeAddedLayers(t *testing.T) {
+	m, err := LoadFile(filepath.Join(GetAssetsDirectory(), "groups.tmx"))
+	assert.NoError(t, err)
+
+	// Layers appended only to the lists by kind follow the loaded layers
+	m.ObjectGroups = append(m.ObjectGroups, &ObjectGroup{ID: 100, Name: "Added", Visible: true, Opacity: 1, ParallaxX: 1, ParallaxY: 1})
+	group := m.Groups[0]
+	group.ObjectGroups = append(group.ObjectGroups, &ObjectGroup{ID: 101, Name: "Added to group", Visible: true, Opacity: 1, ParallaxX: 1, ParallaxY: 1})
+
+	var names []string
+	assert.NoError(t, m.WalkLayers(func(layer MapLayer, state *LayerState) error {
+		names = append(names, layer.GetName())
+		return nil
+	}))
+	assert.Contains(t, names, "Added")
+	assert.Contains(t, names, "Added to group")
+
+	var buf bytes.Buffer
+	assert.NoError(t, m.Encode(&buf))
+	m2, err := LoadReader(GetAssetsDirectory(), &buf)
+	assert.NoError(t, err)
+
+	assert.Len(t, m2.Children, len(m.Children)+1)
+	assert.Equal(t, "Added", m2.Children[len(m2.Children)-1].GetName())
+	if assert.Len(t, m2.Groups, len(m.Groups)) {
+		children := m2.Groups[0].Children
+		assert.Len(t, children, len(group.Children)+1)
+		assert.Equal(t, "Added to group", children[len(children)-1].GetName())
+	}
+}
+
+func TestEncodeLayerEncoding(t *testing.T) {
+	m, err := LoadFile(filepath.Join(GetAssetsDirectory(), "test_wangsets_map.tmx"))
+	assert.NoError(t, err)
+
+	tests := []struct {
+		encoding    string
+		compression string
+	}{
+		{"", ""},
+		{"csv", ""},
+		{"base64", ""},
+		{"base64", "gzip"},
+		{"base64", "zlib"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		assert.NoError(t, m.Encode(&buf, WithLayerEncoding(tt.encoding, tt.compression)))
+
+		m2, err := LoadReader(GetAssetsDirectory(), &buf)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.encoding, m2.Layers[0].Encoding)
+		assert.Equal(t, tt.compression, m2.Layers[0].Compression)
+		assert.Len(t, m2.Layers[0].Tiles, len(m.Layers[0].Tiles))
+		for i, tile := range m.Layers[0].Tiles {
+			assert.Equal(t, layerTileGID(tile), layerTileGID(m2.Layers[0].Tiles[i]))
+		}
+	}
+
+	err = m.Encode(&bytes.Buffer{}, WithLayerEncoding("base64", "zstd"))
+	assert.ErrorIs(t, err, ErrUnknownCompression)
+}
+
+func TestSaveFile(t *testing.T) {
+	m, err := LoadFile(filepath.Join(GetAssetsDirectory(), "font.tmx"))
+	assert.NoError(t, err)
+
+	fileName := filepath.Join(t.TempDir(), "font.tmx")
+	assert.NoError(t, m.SaveFile(fileName))
+
+	m2, err := LoadFile(fileName)
+	assert.NoError(t, err)
+	assert.Equal(t, m.ObjectGroups, m2.ObjectGroups)
+}
+
+func decodeTSX(t *testing.T, r io.Reader) *Tileset {
+	ts := &Tileset{}
+	assert.NoError(t, xml.NewDecoder(r).Decode(ts))
+	return ts
+}
+
+func TestEncodeTileset(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join(GetAssetsDirectory(), "tilesets", "*.tsx"))
+	assert.NoError(t, err)
+	assert.NotEmpty(t, files)
+
+	for _, fileName := range files {
+		t.Run(filepath.Base(fileName), func(t *testing.T) {
+			f, err := os.Open(fileName)
+			assert.NoError(t, err)
+			defer f.Close()
+			ts1 := decodeTSX(t, f)
+
+			var buf1 bytes.Buffer
+			assert.NoError(t, ts1.Encode(&buf1))
+			ts2 := decodeTSX(t, bytes.NewReader(buf1.Bytes()))
+
+			var buf2 bytes.Buffer
+			assert.NoError(t, ts2.Encode(&buf2))
+			assert.Equal(t, buf1.String(), buf2.String())
+			assert.Equal(t, ts1, ts2)
+		})
+	}
+}
+
+func TestSaveTSX(t *testing.T) {
+	m, err := LoadFile(filepath.Join(GetAssetsDirectory(), "test_json_references.tmx"))
+	assert.NoError(t, err)
+
+	// Tileset loaded from JSON must be written as TSX without map specific attributes
+	ts := m.Tilesets[0]
+	assert.True(t, ts.SourceLoaded)
+
+	fileName := filepath.Join(t.TempDir(), "test2.tsx")
+	assert.NoError(t, ts.SaveTSX(fileName))
+
+	data, err := os.ReadFile(fileName)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "firstgid")
+	assert.NotContains(t, string(data), "test2.tsj")
+
+	f, err := os.Open(fileName)
+	assert.NoError(t, err)
+	defer f.Close()
+	saved := decodeTSX(t, f)
+	assert.Equal(t, ts.Name, saved.Name)
+	assert.Equal(t, ts.Image, saved.Image)
+	assert.Equal(t, ts.Tiles, saved.Tiles)
+}
@@ -40,6 +40,12 @@ type Group struct {
 	OffsetX int `xml:"offsetx,attr"`
 	// Rendering offset of the image layer in pixels. Defaults to 0. (since 0.15)
 	OffsetY int `xml:"offsety,attr"`
+	// Horizontal parallax factor for this group. Defaults to 1. (since 1.5)
+	ParallaxX float64 `xml:"parallaxx,attr"`
+	// Vertical parallax factor for this group. Defaults to 1. (since 1.5)
+	ParallaxY float64 `xml:"parallaxy,attr"`
+	// A tint color that is multiplied with any graphics drawn by this layer or any child layers (optional). (since 1.9)
+	TintColor *HexColor `xml:"tintcolor,attr"`
 	// The opacity of the layer as a value from 0 to 1. Defaults to 1.
 	Opacity float32 `xml:"opacity,attr"`
 	// Whether the layer is shown (1) or hidden (0). Defaults to 1.
@@ -47,13 +53,16 @@ type Group struct {
 	// Custom properties
 	Properties Properties `xml:"properties>property"`
 	// Map layers
-	Layers []*Layer `xml:"layer"`
+	Layers []*Layer `xml:"-"`
 	// Map object groups
-	ObjectGroups []*ObjectGroup `xml:"objectgroup"`
+	ObjectGroups []*ObjectGroup `xml:"-"`
 	// Image layers
-	ImageLayers []*ImageLayer `xml:"imagelayer"`
+	ImageLayers []*ImageLayer `xml:"-"`
 	// Group layers
-	Groups []*Group `xml:"group"`
+	Groups []*Group `xml:"-"`
+	// Layers of all kinds in the order they appear in the group, which is the order in which they are rendered.
+	// Layers added only to the lists by kind are encoded and rendered after these layers.
+	Children MapLayers `xml:",any"`
 }
 
 // UnmarshalXML decodes a single XML element beginning with the given start element.
@@ -66,12 +75,13 @@ func (g *Group) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
 	}
 
 	*g = (Group)(item)
+	g.Layers, g.ObjectGroups, g.ImageLayers, g.Groups = g.Children.split()
 
 	return nil
 }
 
 // DecodeGroup decodes Group data. This includes all subgroups and the Layer
-// data for each.
+// and ObjectGroup data for each.
 func (g *Group) DecodeGroup(m *Map) error {
 	for i := 0; i < len(g.Groups); i++ {
 		g := g.Groups[i]
@@ -87,9 +97,17 @@ func (g *Group) DecodeGroup(m *Map) error {
 		}
 	}
 
+	for _, og := range g.ObjectGroups {
+		if err := og.DecodeObjectGroup(m); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// GetLayerByName returns the first tile layer with the given name in the group
+// or any of its subgroups
 func (g *Group) GetLayerByName(name string) *Layer {
 	for _, layer := range g.Layers {
 		if layer.Name == name {
@@ -97,5 +115,11 @@ func (g *Group) GetLayerByName(name string) *Layer {
 		}
 	}
 
+	for _, group := range g.Groups {
+		if layer := group.GetLayerByName(name); layer != nil {
+			return layer
+		}
+	}
+
 	return nil
 }
@@ -2,6 +2,7 @@ package tiled
 
 import (
 	"encoding/hex"
+	"encoding/json"
 	"encoding/xml"
 	"errors"
 	"image/color"
@@ -44,6 +45,12 @@ func (color *HexColor) RGBA() (r, g, b, a uint32) {
 	return color.c.RGBA()
 }
 
+// NRGBA returns the color with channels that are not premultiplied by alpha, as they are stored in the file
+func (color *HexColor) NRGBA() (c color.NRGBA) {
+	c.R, c.G, c.B, c.A = color.c.R, color.c.G, color.c.B, color.c.A
+	return c
+}
+
 func (color *HexColor) String() string {
 	src := []byte{
 		color.c.A,
@@ -80,6 +87,40 @@ func (color *HexColor) MarshalXMLAttr(name xml.Name) (attr xml.Attr, err error)
 	return
 }
 
+// UnmarshalJSON implements json.Unmarshaler
+func (color *HexColor) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	c, err := parseHexColor(s)
+	if err != nil {
+		return err
+	}
+	color.c = c
+	return nil
+}
+
+// MultiplyHexColors returns the color with channels of both colors multiplied, which is how tint
+// colors of nested layers are combined. Nil colors are treated as white, the result is nil if both are nil.
+func MultiplyHexColors(a, b *HexColor) *HexColor {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	mul := func(x, y uint8) uint8 {
+		return uint8((uint16(x)*uint16(y) + 127) / 255)
+	}
+	return &HexColor{c: color.RGBA{
+		R: mul(a.c.R, b.c.R),
+		G: mul(a.c.G, b.c.G),
+		B: mul(a.c.B, b.c.B),
+		A: mul(a.c.A, b.c.A),
+	}}
+}
+
 func parseHexColor(s string) (c color.RGBA, err error) {
 	hexToByte := func(b byte) byte {
 		switch {
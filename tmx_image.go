@@ -23,9 +23,19 @@ SOFTWARE.
 package tiled
 
 import (
+	"bytes"
 	"encoding/xml"
+	"errors"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"strings"
 )
 
+// ErrNoImageData error is returned when decoding an image that is not embedded
+var ErrNoImageData = errors.New("tiled: image has no embedded data")
+
 // ImageLayer is a layer consisting of a single image.
 type ImageLayer struct {
 	// Unique ID of the layer.
@@ -40,6 +50,12 @@ type ImageLayer struct {
 	OffsetX int `xml:"offsetx,attr"`
 	// Rendering offset of the image layer in pixels. Defaults to 0. (since 0.15)
 	OffsetY int `xml:"offsety,attr"`
+	// Horizontal parallax factor for this image layer. Defaults to 1. (since 1.5)
+	ParallaxX float64 `xml:"parallaxx,attr"`
+	// Vertical parallax factor for this image layer. Defaults to 1. (since 1.5)
+	ParallaxY float64 `xml:"parallaxy,attr"`
+	// A tint color that is multiplied with the image drawn by this layer (optional). (since 1.9)
+	TintColor *HexColor `xml:"tintcolor,attr"`
 	// The x position of the image layer in pixels. (deprecated since 0.15)
 	X int `xml:"x,attr"`
 	// The y position of the image layer in pixels. (deprecated since 0.15)
@@ -48,6 +64,10 @@ type ImageLayer struct {
 	Opacity float32 `xml:"opacity,attr"`
 	// Whether the layer is shown (1) or hidden (0). Defaults to 1.
 	Visible bool `xml:"visible,attr"`
+	// Whether the image drawn by this layer is repeated along the X axis. (since 1.8)
+	RepeatX bool `xml:"repeatx,attr"`
+	// Whether the image drawn by this layer is repeated along the Y axis. (since 1.8)
+	RepeatY bool `xml:"repeaty,attr"`
 	// Custom properties
 	Properties Properties `xml:"properties>property"`
 	// The group image
@@ -81,6 +101,40 @@ type Image struct {
 	Width int `xml:"width,attr"`
 	// The image height in pixels (optional)
 	Height int `xml:"height,attr"`
-	// Embedded image content
-	Data *Data `xml:"data,attr"`
+	// Embedded image content, usually base64 encoded, in the format given by Format
+	Data *Data `xml:"data"`
+}
+
+// IsEmbedded reports whether the image data is embedded in the map or tileset instead of being
+// loaded from the Source file.
+func (img *Image) IsEmbedded() bool {
+	return img.Data != nil
+}
+
+// DecodeData decodes the embedded image using the declared format. Images in other formats, or with
+// no declared format, are decoded with any image format registered in the image package.
+func (img *Image) DecodeData() (image.Image, error) {
+	if img.Data == nil {
+		return nil, ErrNoImageData
+	}
+	if img.Data.Encoding != "base64" {
+		return nil, ErrUnknownEncoding
+	}
+
+	data, err := img.Data.decodeBase64()
+	if err != nil {
+		return nil, err
+	}
+	r := bytes.NewReader(data)
+
+	switch strings.ToLower(img.Format) {
+	case "png":
+		return png.Decode(r)
+	case "gif":
+		return gif.Decode(r)
+	case "jpg", "jpeg":
+		return jpeg.Decode(r)
+	}
+	decoded, _, err := image.Decode(r)
+	return decoded, err
 }
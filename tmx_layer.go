@@ -50,8 +50,10 @@ type LayerTile struct {
 	HorizontalFlip bool
 	// Vertical tile image flip
 	VerticalFlip bool
-	// Diagonal tile image flip
+	// Diagonal tile image flip, which rotates the tile image by 60 degrees clockwise in hexagonal maps
 	DiagonalFlip bool
+	// Tile image rotated by 120 degrees clockwise in hexagonal maps (since 1.1)
+	RotatedHexagonal120 bool
 	// Tile is nil
 	Nil       bool
 	X         int
@@ -85,15 +87,26 @@ type Layer struct {
 	OffsetX int `xml:"offsetx,attr"`
 	// Rendering offset for this layer in pixels. Defaults to 0. (since 0.14)
 	OffsetY int `xml:"offsety,attr"`
+	// Horizontal parallax factor for this layer. Defaults to 1. (since 1.5)
+	ParallaxX float64 `xml:"parallaxx,attr"`
+	// Vertical parallax factor for this layer. Defaults to 1. (since 1.5)
+	ParallaxY float64 `xml:"parallaxy,attr"`
+	// A tint color that is multiplied with the tiles drawn by this layer (optional). (since 1.9)
+	TintColor *HexColor `xml:"tintcolor,attr"`
 	// Custom properties
 	Properties Properties `xml:"properties>property"`
 	// This is the attribute you'd like to use, not Data. Tile entry at (x,y) is obtained using l.DecodedTiles[y*map.Width+x].
 	Tiles []*LayerTile
+	// The encoding used to store the tile layer data: "csv", "base64" or empty for XML. Used when the map is encoded.
+	Encoding string `xml:"-"`
+	// The compression used to store the tile layer data: "gzip", "zlib" or empty. Used when the map is encoded.
+	Compression string `xml:"-"`
 	// Data
 	data *Data
 
 	Chunks []*Chunk
 
+	// Bounding box of the non-empty tiles of the layer in map tile coordinates, nil if the layer is empty
 	Border *Border
 
 	// Set when all entries of the layer are NilTile
@@ -205,6 +218,7 @@ func (l *Layer) DecodeLayer(m *Map) error {
 		if err := l.decodeTiles(); err != nil {
 			return err
 		}
+		l.Border = l.ComputeBorder()
 	} else {
 		for _, chunk := range l.Chunks {
 			if err := chunk.DecodeChunk(l); err != nil {
@@ -216,18 +230,21 @@ func (l *Layer) DecodeLayer(m *Map) error {
 	return nil
 }
 
+// ParseLayerInInfiniteMode fills layer tiles from its chunks. Tile entry at map tile coordinates (x,y)
+// is stored at l.Tiles[(y-map.Border.MinY)*map.Width+(x-map.Border.MinX)], so that chunks with negative
+// coordinates are kept as well.
 func (l *Layer) ParseLayerInInfiniteMode(m *Map) error {
 	size := l._map.Width * l._map.Height
 	l.Tiles = make([]*LayerTile, size)
 
+	border := l._map.Border
 	for _, chunk := range l.Chunks {
 		for _, tile := range chunk.Tiles {
-			index := tile.Y*l._map.Width + tile.X
-			if index >= size {
+			if tile.Nil || !border.Contains(tile.X, tile.Y) {
 				continue
 			}
 
-			l.Tiles[index] = tile
+			l.Tiles[(tile.Y-border.MinY)*l._map.Width+tile.X-border.MinX] = tile
 		}
 	}
 
@@ -272,8 +289,12 @@ func (l *Layer) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
 	}
 
 	*l = (Layer)(item.internalLayer)
-	l.data = &item.Data.Data
-	l.Chunks = item.Data.Chunks
+	if item.Data != nil {
+		l.data = &item.Data.Data
+		l.Encoding = item.Data.Encoding
+		l.Compression = item.Data.Compression
+		l.Chunks = item.Data.Chunks
+	}
 	return nil
 }
 
@@ -289,14 +310,29 @@ func (t *LayerTile) GetTileRect() image.Rectangle {
 	return t.Tileset.GetTileRect(t.ID)
 }
 
+// ComputeBorder returns the bounding box of the non-empty tiles of the layer, or nil if the layer is empty.
 func (l *Layer) ComputeBorder() *Border {
-	firstTile := l.Tiles[0]
+	var firstTile *LayerTile
+	for _, tile := range l.Tiles {
+		if !tile.Nil {
+			firstTile = tile
+			break
+		}
+	}
+	if firstTile == nil {
+		return nil
+	}
+
 	minX := firstTile.X
 	maxX := firstTile.X
 	minY := firstTile.Y
 	maxY := firstTile.Y
 
 	for _, tile := range l.Tiles {
+		if tile.Nil {
+			continue
+		}
+
 		if tile.X < minX {
 			minX = tile.X
 		}
@@ -23,6 +23,7 @@ SOFTWARE.
 package tiled
 
 import (
+	"bufio"
 	"encoding/xml"
 	"errors"
 	"fmt"
@@ -31,11 +32,12 @@ import (
 )
 
 const (
-	tileHorizontalFlipMask = 0x80000000
-	tileVerticalFlipMask   = 0x40000000
-	tileDiagonalFlipMask   = 0x20000000
-	tileFlip               = tileHorizontalFlipMask | tileVerticalFlipMask | tileDiagonalFlipMask
-	tileGIDMask            = 0x0fffffff
+	tileHorizontalFlipMask      = 0x80000000
+	tileVerticalFlipMask        = 0x40000000
+	tileDiagonalFlipMask        = 0x20000000
+	tileRotatedHexagonal120Mask = 0x10000000
+	tileFlip                    = tileHorizontalFlipMask | tileVerticalFlipMask | tileDiagonalFlipMask | tileRotatedHexagonal120Mask
+	tileGIDMask                 = 0x0fffffff
 )
 
 // ErrInvalidTileGID error is returned when tile GID is not found
@@ -108,24 +110,40 @@ type Map struct {
 	StaggerIndex StaggerIndexType `xml:"staggerindex,attr"`
 	// The background color of the map. (since 0.9, optional, may include alpha value since 0.15 in the form #AARRGGBB)
 	BackgroundColor *HexColor `xml:"backgroundcolor,attr"`
+	// X coordinate of the parallax origin in pixels. Defaults to 0. (since 1.8)
+	ParallaxOriginX float64 `xml:"parallaxoriginx,attr"`
+	// Y coordinate of the parallax origin in pixels. Defaults to 0. (since 1.8)
+	ParallaxOriginY float64 `xml:"parallaxoriginy,attr"`
+	// Stores the next available ID for new layers. This number is stored to prevent reuse of the same ID after layers have been removed. (since 1.2)
+	NextLayerID uint32 `xml:"nextlayerid,attr"`
 	// Stores the next available ID for new objects. This number is stored to prevent reuse of the same ID after objects have been removed. (since 0.11)
 	NextObjectID uint32 `xml:"nextobjectid,attr"`
 	IsInfinite   bool   `xml:"infinite,attr"`
+	// The compression level to use for tile layer data. Defaults to -1, which means to use the algorithm default. (since 1.3)
+	CompressionLevel int `xml:"compressionlevel,attr"`
 	// Custom properties
 	Properties *Properties `xml:"properties>property"`
 	// Map tilesets
 	Tilesets []*Tileset `xml:"tileset"`
 	// Map layers
-	Layers []*Layer `xml:"layer"`
+	Layers []*Layer `xml:"-"`
 	// Map object groups
-	ObjectGroups []*ObjectGroup `xml:"objectgroup"`
+	ObjectGroups []*ObjectGroup `xml:"-"`
 	// Image layers
-	ImageLayers []*ImageLayer `xml:"imagelayer"`
+	ImageLayers []*ImageLayer `xml:"-"`
 	// Group layers
-	Groups []*Group `xml:"group"`
+	Groups []*Group `xml:"-"`
+	// Layers of all kinds in the order they appear in the map, which is the order in which they are rendered.
+	// Layers added only to the lists by kind are encoded and rendered after these layers.
+	Children MapLayers `xml:",any"`
 
+	// All tile layers of the map, including layers nested in groups at any depth, in the order they appear in the map
 	AllLayers []*Layer
-	Border    *Border
+	// Bounding box of all chunks of an infinite map in tile coordinates, nil for finite maps
+	Border *Border
+
+	// Width and height stored in the file of an infinite map, before they are replaced by the size of its chunks
+	declaredWidth, declaredHeight int
 }
 
 func (m *Map) initTileset(ts *Tileset) error {
@@ -144,9 +162,13 @@ func (m *Map) initTileset(ts *Tileset) error {
 	}
 	defer f.Close()
 
-	d := xml.NewDecoder(f)
-
-	if err := d.Decode(ts); err != nil {
+	r := bufio.NewReader(f)
+	if detectFormat(sourcePath, r) == formatJSON {
+		err = ts.decodeJSON(r)
+	} else {
+		err = xml.NewDecoder(r).Decode(ts)
+	}
+	if err != nil {
 		return err
 	}
 
@@ -171,12 +193,13 @@ func (m *Map) TileGIDToTile(gid uint32) (*LayerTile, error) {
 				return nil, err
 			}
 			return &LayerTile{
-				ID:             gidBare - ts.FirstGID,
-				Tileset:        ts,
-				HorizontalFlip: gid&tileHorizontalFlipMask != 0,
-				VerticalFlip:   gid&tileVerticalFlipMask != 0,
-				DiagonalFlip:   gid&tileDiagonalFlipMask != 0,
-				Nil:            false,
+				ID:                  gidBare - ts.FirstGID,
+				Tileset:             ts,
+				HorizontalFlip:      gid&tileHorizontalFlipMask != 0,
+				VerticalFlip:        gid&tileVerticalFlipMask != 0,
+				DiagonalFlip:        gid&tileDiagonalFlipMask != 0,
+				RotatedHexagonal120: gid&tileRotatedHexagonal120Mask != 0,
+				Nil:                 false,
 			}, nil
 		}
 	}
@@ -254,47 +277,56 @@ func (m *Map) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
 		return err
 	}
 
+	*m = (Map)(item)
+	m.Layers, m.ObjectGroups, m.ImageLayers, m.Groups = m.Children.split()
+	return m.decodeLayers()
+}
+
+// decodeLayers decodes the data of all layers once the map structure has been
+// read, regardless of the format it was stored in.
+func (m *Map) decodeLayers() error {
 	// Decode Groups data
-	for i := 0; i < len(item.Groups); i++ {
-		g := item.Groups[i]
-		if err := g.DecodeGroup((*Map)(&item)); err != nil {
+	for i := 0; i < len(m.Groups); i++ {
+		g := m.Groups[i]
+		if err := g.DecodeGroup(m); err != nil {
 			return err
 		}
 	}
 
 	// Decode layers data
-	for i := 0; i < len(item.Layers); i++ {
-		l := item.Layers[i]
-		if err := l.DecodeLayer((*Map)(&item)); err != nil {
+	for i := 0; i < len(m.Layers); i++ {
+		l := m.Layers[i]
+		if err := l.DecodeLayer(m); err != nil {
 			return err
 		}
 	}
 
 	// Decode object groups.
-	for _, g := range item.ObjectGroups {
-		if err := g.DecodeObjectGroup((*Map)(&item)); err != nil {
+	for _, g := range m.ObjectGroups {
+		if err := g.DecodeObjectGroup(m); err != nil {
 			return err
 		}
 	}
 
-	allLayers := append([]*Layer{}, item.Layers...)
-
-	for _, group := range item.Groups {
-		allLayers = append(allLayers, group.Layers...)
-	}
-
-	item.AllLayers = allLayers
+	m.AllLayers = nil
+	_ = m.WalkLayers(func(l MapLayer, _ *LayerState) error {
+		if layer, ok := l.(*Layer); ok {
+			m.AllLayers = append(m.AllLayers, layer)
+		}
+		return nil
+	})
 
-	if item.IsInfinite {
-		castMap := (*Map)(&item)
-		castMap.RefreshMapWidthInInfiniteMode()
+	if m.IsInfinite {
+		m.declaredWidth, m.declaredHeight = m.Width, m.Height
+		m.RefreshMapWidthInInfiniteMode()
 
-		for _, layer := range item.AllLayers {
-			layer.ParseLayerInInfiniteMode(castMap)
+		for _, layer := range m.AllLayers {
+			if err := layer.ParseLayerInInfiniteMode(m); err != nil {
+				return err
+			}
 		}
 	}
 
-	*m = (Map)(item)
 	return nil
 }
 
@@ -0,0 +1,336 @@
+/*
+Copyright (c) 2023 Lauris Bukšis-Haberkorns <lauris@nix.lv>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package tiled
+
+import (
+	"encoding/xml"
+	"errors"
+)
+
+// LayerKind is the kind of a map layer
+type LayerKind string
+
+const (
+	// LayerKindTile is a tile layer (Layer)
+	LayerKindTile LayerKind = "tilelayer"
+	// LayerKindObjectGroup is an object group (ObjectGroup)
+	LayerKindObjectGroup LayerKind = "objectgroup"
+	// LayerKindImage is an image layer (ImageLayer)
+	LayerKindImage LayerKind = "imagelayer"
+	// LayerKindGroup is a group layer (Group)
+	LayerKindGroup LayerKind = "group"
+)
+
+// MapLayer is implemented by all kinds of map layers: *Layer, *ObjectGroup, *ImageLayer and *Group
+type MapLayer interface {
+	// Kind returns the kind of the layer.
+	Kind() LayerKind
+	// GetID returns the unique ID of the layer.
+	GetID() uint32
+	// GetName returns the name of the layer.
+	GetName() string
+	// GetOpacity returns the opacity of the layer as a value from 0 to 1.
+	GetOpacity() float32
+	// IsVisible returns whether the layer is shown.
+	IsVisible() bool
+	// GetOffset returns the rendering offset of the layer in pixels.
+	GetOffset() (int, int)
+	// GetParallax returns the horizontal and vertical parallax factors of the layer. Layers loaded from
+	// files default to 1, but layers created in code have to set their factors, as zero factors keep
+	// the layer fixed to the screen instead of moving it with the camera.
+	GetParallax() (float64, float64)
+	// GetTintColor returns the tint color of the layer, or nil if it has none.
+	GetTintColor() *HexColor
+}
+
+var (
+	_ MapLayer = &Layer{}
+	_ MapLayer = &ObjectGroup{}
+	_ MapLayer = &ImageLayer{}
+	_ MapLayer = &Group{}
+)
+
+// MapLayers contains layers of all kinds in the order they appear in the map or group,
+// which is the order in which they are rendered by Tiled
+type MapLayers []MapLayer
+
+// UnmarshalXML decodes a single layer element of any kind and appends it to the list.
+// Elements that are not layers are skipped.
+func (ml *MapLayers) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var l MapLayer
+	switch start.Name.Local {
+	case "layer":
+		l = &Layer{}
+	case "objectgroup":
+		l = &ObjectGroup{}
+	case "imagelayer":
+		l = &ImageLayer{}
+	case "group":
+		l = &Group{}
+	default:
+		return d.Skip()
+	}
+
+	if err := d.DecodeElement(l, &start); err != nil {
+		return err
+	}
+
+	*ml = append(*ml, l)
+	return nil
+}
+
+// SkipGroup is used as a return value from WalkLayersFunc to indicate that
+// the layers of the group passed to the function are to be skipped.
+var SkipGroup = errors.New("tiled: skip this group")
+
+// LayerState is the effective state of a layer, accumulated from the layer
+// itself and all of its parent groups
+type LayerState struct {
+	// Parent groups of the layer, starting with the top level group. Empty for top level layers.
+	Parents []*Group
+	// Effective opacity: opacity of the layer multiplied by opacities of its parents.
+	Opacity float32
+	// Effective visibility: the layer and all of its parents are visible.
+	Visible bool
+	// Effective horizontal offset in pixels: sum of offsets of the layer and its parents.
+	OffsetX int
+	// Effective vertical offset in pixels: sum of offsets of the layer and its parents.
+	OffsetY int
+	// Effective horizontal parallax factor: parallax factor of the layer multiplied by factors of its parents.
+	ParallaxX float64
+	// Effective vertical parallax factor: parallax factor of the layer multiplied by factors of its parents.
+	ParallaxY float64
+	// Effective tint color: tint color of the layer multiplied by tint colors of its parents. Nil if none of them is tinted.
+	TintColor *HexColor
+}
+
+// WalkLayersFunc is the type of the function called by WalkLayers to visit each layer.
+// If the function returns SkipGroup when visiting a group, the layers of that group are
+// not visited. Any other error stops walking and is returned by WalkLayers.
+type WalkLayersFunc func(layer MapLayer, state *LayerState) error
+
+// WalkLayers calls fn for all layers of the map, including groups and layers nested in
+// groups at any depth, in the order they appear in the map. Groups are visited before
+// their layers.
+func (m *Map) WalkLayers(fn WalkLayersFunc) error {
+	root := &LayerState{
+		Opacity:   1,
+		Visible:   true,
+		ParallaxX: 1,
+		ParallaxY: 1,
+	}
+	return walkLayers(joinMapLayers(m.Children, m.Layers, m.ObjectGroups, m.ImageLayers, m.Groups), root, fn)
+}
+
+// WalkLayers calls fn for all layers of the group, including nested groups and their layers,
+// in the order they appear in the group. The group itself is not visited and states are
+// relative to it.
+func (g *Group) WalkLayers(fn WalkLayersFunc) error {
+	root := &LayerState{
+		Opacity:   1,
+		Visible:   true,
+		ParallaxX: 1,
+		ParallaxY: 1,
+	}
+	return walkLayers(joinMapLayers(g.Children, g.Layers, g.ObjectGroups, g.ImageLayers, g.Groups), root, fn)
+}
+
+func walkLayers(layers MapLayers, parent *LayerState, fn WalkLayersFunc) error {
+	for _, l := range layers {
+		offsetX, offsetY := l.GetOffset()
+		parallaxX, parallaxY := l.GetParallax()
+		state := &LayerState{
+			Parents:   parent.Parents,
+			Opacity:   parent.Opacity * l.GetOpacity(),
+			Visible:   parent.Visible && l.IsVisible(),
+			OffsetX:   parent.OffsetX + offsetX,
+			OffsetY:   parent.OffsetY + offsetY,
+			ParallaxX: parent.ParallaxX * parallaxX,
+			ParallaxY: parent.ParallaxY * parallaxY,
+			TintColor: MultiplyHexColors(parent.TintColor, l.GetTintColor()),
+		}
+
+		if err := fn(l, state); errors.Is(err, SkipGroup) {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		g, ok := l.(*Group)
+		if !ok {
+			continue
+		}
+
+		groupState := *state
+		groupState.Parents = make([]*Group, 0, len(parent.Parents)+1)
+		groupState.Parents = append(append(groupState.Parents, parent.Parents...), g)
+		if err := walkLayers(joinMapLayers(g.Children, g.Layers, g.ObjectGroups, g.ImageLayers, g.Groups), &groupState, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// split returns layers separated by their kind
+func (ml MapLayers) split() (layers []*Layer, objectGroups []*ObjectGroup, imageLayers []*ImageLayer, groups []*Group) {
+	for _, l := range ml {
+		switch l := l.(type) {
+		case *Layer:
+			layers = append(layers, l)
+		case *ObjectGroup:
+			objectGroups = append(objectGroups, l)
+		case *ImageLayer:
+			imageLayers = append(imageLayers, l)
+		case *Group:
+			groups = append(groups, l)
+		}
+	}
+	return
+}
+
+// joinMapLayers returns layers in the order they are stored, followed by layers of the lists by kind that
+// are missing from it, for example layers appended to these lists after loading or all layers of maps built
+// in code. Missing layers are ordered by their kind.
+func joinMapLayers(children MapLayers, layers []*Layer, objectGroups []*ObjectGroup, imageLayers []*ImageLayer, groups []*Group) MapLayers {
+	ordered := make(map[MapLayer]bool, len(children))
+	for _, l := range children {
+		ordered[l] = true
+	}
+
+	// Capacity is limited, so that appending does not change layers stored after the ordered list
+	joined := children[:len(children):len(children)]
+	join := func(l MapLayer) {
+		if !ordered[l] {
+			joined = append(joined, l)
+		}
+	}
+	for _, l := range layers {
+		join(l)
+	}
+	for _, og := range objectGroups {
+		join(og)
+	}
+	for _, il := range imageLayers {
+		join(il)
+	}
+	for _, g := range groups {
+		join(g)
+	}
+	return joined
+}
+
+// Kind returns LayerKindTile
+func (l *Layer) Kind() LayerKind { return LayerKindTile }
+
+// GetID returns the unique ID of the layer
+func (l *Layer) GetID() uint32 { return l.ID }
+
+// GetName returns the name of the layer
+func (l *Layer) GetName() string { return l.Name }
+
+// GetOpacity returns the opacity of the layer
+func (l *Layer) GetOpacity() float32 { return l.Opacity }
+
+// IsVisible returns whether the layer is shown
+func (l *Layer) IsVisible() bool { return l.Visible }
+
+// GetOffset returns the rendering offset of the layer
+func (l *Layer) GetOffset() (int, int) { return l.OffsetX, l.OffsetY }
+
+// GetParallax returns the parallax factors of the layer
+func (l *Layer) GetParallax() (float64, float64) { return l.ParallaxX, l.ParallaxY }
+
+// GetTintColor returns the tint color of the layer
+func (l *Layer) GetTintColor() *HexColor { return l.TintColor }
+
+// Kind returns LayerKindObjectGroup
+func (g *ObjectGroup) Kind() LayerKind { return LayerKindObjectGroup }
+
+// GetID returns the unique ID of the object group
+func (g *ObjectGroup) GetID() uint32 { return g.ID }
+
+// GetName returns the name of the object group
+func (g *ObjectGroup) GetName() string { return g.Name }
+
+// GetOpacity returns the opacity of the object group
+func (g *ObjectGroup) GetOpacity() float32 { return g.Opacity }
+
+// IsVisible returns whether the object group is shown
+func (g *ObjectGroup) IsVisible() bool { return g.Visible }
+
+// GetOffset returns the rendering offset of the object group
+func (g *ObjectGroup) GetOffset() (int, int) { return g.OffsetX, g.OffsetY }
+
+// GetParallax returns the parallax factors of the object group
+func (g *ObjectGroup) GetParallax() (float64, float64) { return g.ParallaxX, g.ParallaxY }
+
+// GetTintColor returns the tint color of the object group
+func (g *ObjectGroup) GetTintColor() *HexColor { return g.TintColor }
+
+// Kind returns LayerKindImage
+func (l *ImageLayer) Kind() LayerKind { return LayerKindImage }
+
+// GetID returns the unique ID of the image layer
+func (l *ImageLayer) GetID() uint32 { return l.ID }
+
+// GetName returns the name of the image layer
+func (l *ImageLayer) GetName() string { return l.Name }
+
+// GetOpacity returns the opacity of the image layer
+func (l *ImageLayer) GetOpacity() float32 { return l.Opacity }
+
+// IsVisible returns whether the image layer is shown
+func (l *ImageLayer) IsVisible() bool { return l.Visible }
+
+// GetOffset returns the rendering offset of the image layer
+func (l *ImageLayer) GetOffset() (int, int) { return l.OffsetX, l.OffsetY }
+
+// GetParallax returns the parallax factors of the image layer
+func (l *ImageLayer) GetParallax() (float64, float64) { return l.ParallaxX, l.ParallaxY }
+
+// GetTintColor returns the tint color of the image layer
+func (l *ImageLayer) GetTintColor() *HexColor { return l.TintColor }
+
+// Kind returns LayerKindGroup
+func (g *Group) Kind() LayerKind { return LayerKindGroup }
+
+// GetID returns the unique ID of the group
+func (g *Group) GetID() uint32 { return g.ID }
+
+// GetName returns the name of the group
+func (g *Group) GetName() string { return g.Name }
+
+// GetOpacity returns the opacity of the group
+func (g *Group) GetOpacity() float32 { return g.Opacity }
+
+// IsVisible returns whether the group is shown
+func (g *Group) IsVisible() bool { return g.Visible }
+
+// GetOffset returns the rendering offset of the group
+func (g *Group) GetOffset() (int, int) { return g.OffsetX, g.OffsetY }
+
+// GetParallax returns the parallax factors of the group
+func (g *Group) GetParallax() (float64, float64) { return g.ParallaxX, g.ParallaxY }
+
+// GetTintColor returns the tint color of the group
+func (g *Group) GetTintColor() *HexColor { return g.TintColor }
@@ -23,6 +23,7 @@ SOFTWARE.
 package tiled
 
 import (
+	"bufio"
 	"encoding/xml"
 	"errors"
 	"path/filepath"
@@ -53,6 +54,12 @@ type ObjectGroup struct {
 	OffsetX int `xml:"offsetx,attr"`
 	// Rendering offset for this layer in pixels. Defaults to 0. (since 0.14)
 	OffsetY int `xml:"offsety,attr"`
+	// Horizontal parallax factor for this object group. Defaults to 1. (since 1.5)
+	ParallaxX float64 `xml:"parallaxx,attr"`
+	// Vertical parallax factor for this object group. Defaults to 1. (since 1.5)
+	ParallaxY float64 `xml:"parallaxy,attr"`
+	// A tint color that is multiplied with the objects drawn by this layer (optional). (since 1.9)
+	TintColor *HexColor `xml:"tintcolor,attr"`
 	// Whether the objects are drawn according to the order of appearance ("index") or sorted by their y-coordinate ("topdown"). Defaults to "topdown".
 	DrawOrder string `xml:"draworder,attr"`
 	// Custom properties
@@ -126,6 +133,8 @@ type Object struct {
 	Properties Properties `xml:"properties>property"`
 	// Used to mark an object as an ellipse. The existing x, y, width and height attributes are used to determine the size of the ellipse.
 	Ellipses []*Ellipse `xml:"ellipse"`
+	// Used to mark an object as a point. The existing x and y attributes are used to determine the position of the point. (since 1.1)
+	PointMarkers []*PointMarker `xml:"point"`
 	// Polygons
 	Polygons []*Polygon `xml:"polygon"`
 	// Poly lines
@@ -153,8 +162,13 @@ func (o *Object) initTemplate(m *Map) error {
 	}
 	defer f.Close()
 
-	d := xml.NewDecoder(f)
-	if err := d.Decode(&o.Template); err != nil {
+	r := bufio.NewReader(f)
+	if detectFormat(sourcePath, r) == formatJSON {
+		o.Template, err = decodeJSONTemplate(r)
+	} else {
+		err = xml.NewDecoder(r).Decode(&o.Template)
+	}
+	if err != nil {
 		return err
 	}
 	o.TemplateLoaded = true
@@ -186,6 +200,9 @@ func (o *Object) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
 // Ellipse is used to mark an object as an ellipse.
 type Ellipse struct{}
 
+// PointMarker is used to mark an object as a point.
+type PointMarker struct{}
+
 // Polygon object is made up of a space-delimited list of x,y coordinates. The origin for these coordinates is the location of the parent object.
 // By default, the first point is created as 0,0 denoting that the point will originate exactly where the object is placed.
 type Polygon struct {
@@ -1,11 +1,58 @@
 package tiled
 
 import (
+	"encoding/xml"
 	"errors"
 	"image"
 	"path/filepath"
 )
 
+// TileRenderSize is the size used to render tiles of a tileset
+type TileRenderSize string
+
+const (
+	// TileRenderSizeTile renders tiles at their own size
+	TileRenderSizeTile TileRenderSize = "tile"
+	// TileRenderSizeGrid renders tiles at the size of the map grid cells
+	TileRenderSizeGrid TileRenderSize = "grid"
+)
+
+// FillMode is the way tiles are scaled when they are rendered at a size different from their own
+type FillMode string
+
+const (
+	// FillModeStretch stretches tiles to fill the rendered size
+	FillModeStretch FillMode = "stretch"
+	// FillModePreserveAspectFit scales tiles to fit the rendered size, keeping their aspect ratio
+	FillModePreserveAspectFit FillMode = "preserve-aspect-fit"
+)
+
+// ObjectAlignment is the point of a tile object image that is placed at the object position
+type ObjectAlignment string
+
+const (
+	// ObjectAlignmentUnspecified is bottom-left in orthogonal maps and bottom in isometric maps
+	ObjectAlignmentUnspecified ObjectAlignment = "unspecified"
+	// ObjectAlignmentTopLeft is top-left corner
+	ObjectAlignmentTopLeft ObjectAlignment = "topleft"
+	// ObjectAlignmentTop is center of the top edge
+	ObjectAlignmentTop ObjectAlignment = "top"
+	// ObjectAlignmentTopRight is top-right corner
+	ObjectAlignmentTopRight ObjectAlignment = "topright"
+	// ObjectAlignmentLeft is center of the left edge
+	ObjectAlignmentLeft ObjectAlignment = "left"
+	// ObjectAlignmentCenter is center of the image
+	ObjectAlignmentCenter ObjectAlignment = "center"
+	// ObjectAlignmentRight is center of the right edge
+	ObjectAlignmentRight ObjectAlignment = "right"
+	// ObjectAlignmentBottomLeft is bottom-left corner
+	ObjectAlignmentBottomLeft ObjectAlignment = "bottomleft"
+	// ObjectAlignmentBottom is center of the bottom edge
+	ObjectAlignmentBottom ObjectAlignment = "bottom"
+	// ObjectAlignmentBottomRight is bottom-right corner
+	ObjectAlignmentBottomRight ObjectAlignment = "bottomright"
+)
+
 // Tileset is collection of tiles
 type Tileset struct {
 	// Base directory
@@ -40,8 +87,20 @@ type Tileset struct {
 	TileCount int `xml:"tilecount,attr"`
 	// The number of tile columns in the tileset. For image collection tilesets it is editable and is used when displaying the tileset. (since 0.15)
 	Columns int `xml:"columns,attr"`
+	// Controls the alignment for tile objects. Defaults to "unspecified", which means bottom-left in orthogonal
+	// maps and bottom in isometric maps. (since 1.4)
+	ObjectAlignment ObjectAlignment `xml:"objectalignment,attr"`
+	// The size to use when rendering tiles from this tileset on a tile layer, "tile" or "grid". Defaults to "tile". (since 1.9)
+	TileRenderSize TileRenderSize `xml:"tilerendersize,attr"`
+	// The fill mode to use when rendering tiles from this tileset, "stretch" or "preserve-aspect-fit".
+	// Only relevant when the tiles are not rendered at their native size. Defaults to "stretch". (since 1.9)
+	FillMode FillMode `xml:"fillmode,attr"`
 	// Offset in pixels, to be applied when drawing a tile from the related tileset. When not present, no offset is applied.
 	TileOffset *TilesetTileOffset `xml:"tileoffset"`
+	// Orientation and size of the grid used to show tile overlays for terrain and collision information (optional).
+	Grid *TilesetGrid `xml:"grid"`
+	// Transformations allowed for tiles of this tileset when they are placed on the map (optional). (since 1.5)
+	Transformations *TilesetTransformations `xml:"transformations"`
 	// Custom properties
 	Properties Properties `xml:"properties>property"`
 	// Embedded image
@@ -54,6 +113,24 @@ type Tileset struct {
 	WangSets WangSets `xml:"wangsets>wangset"`
 }
 
+// UnmarshalXML decodes a single XML element beginning with the given start element.
+func (ts *Tileset) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	// External tilesets are decoded into the map tileset, which keeps its first GID and source
+	item := aliasTileset{
+		baseDir:  ts.baseDir,
+		FirstGID: ts.FirstGID,
+		Source:   ts.Source,
+	}
+	item.SetDefaults()
+
+	if err := d.DecodeElement(&item, &start); err != nil {
+		return err
+	}
+
+	*ts = (Tileset)(item)
+	return nil
+}
+
 // BaseDir returns the base directory.
 func (ts *Tileset) BaseDir() string {
 	return ts.baseDir
@@ -77,6 +154,29 @@ type TilesetTileOffset struct {
 	Y int `xml:"y,attr"`
 }
 
+// TilesetGrid is used to specify how tile overlays for terrain and collision information are rendered
+type TilesetGrid struct {
+	// Orientation of the grid, "orthogonal" or "isometric"
+	Orientation string `xml:"orientation,attr"`
+	// Width of a grid cell
+	Width int `xml:"width,attr"`
+	// Height of a grid cell
+	Height int `xml:"height,attr"`
+}
+
+// TilesetTransformations describes which transformations can be applied to the tiles of a tileset,
+// for example when choosing a random tile while editing the map
+type TilesetTransformations struct {
+	// Whether the tiles can be flipped horizontally
+	HFlip bool `xml:"hflip,attr"`
+	// Whether the tiles can be flipped vertically
+	VFlip bool `xml:"vflip,attr"`
+	// Whether the tiles can be rotated in 90-degree increments, or 60-degree increments in hexagonal maps
+	Rotate bool `xml:"rotate,attr"`
+	// Whether untransformed tiles remain preferred, otherwise transformed tiles are used to produce more variations
+	PreferUntransformed bool `xml:"preferuntransformed,attr"`
+}
+
 // Terrain type
 type Terrain struct {
 	// The name of the terrain type.
@@ -103,6 +203,14 @@ type TilesetTile struct {
 	Terrain string `xml:"terrain,attr"`
 	// A percentage indicating the probability that this tile is chosen when it competes with others while editing with the terrain tool. (optional) (since 0.9)
 	Probability float32 `xml:"probability,attr"`
+	// The X position of the sub-rectangle representing this tile. Defaults to 0. (since 1.9)
+	X int `xml:"x,attr"`
+	// The Y position of the sub-rectangle representing this tile. Defaults to 0. (since 1.9)
+	Y int `xml:"y,attr"`
+	// The width of the sub-rectangle representing this tile. Zero uses the whole image. (since 1.9)
+	Width int `xml:"width,attr"`
+	// The height of the sub-rectangle representing this tile. Zero uses the whole image. (since 1.9)
+	Height int `xml:"height,attr"`
 	// Custom properties
 	Properties Properties `xml:"properties>property"`
 	// Embedded image
@@ -121,11 +229,28 @@ type AnimationFrame struct {
 	Duration uint32 `xml:"duration,attr"`
 }
 
-// GetTileRect returns a rectangle that contains the tile in the tileset.Image
+// GetTileRect returns a rectangle that contains the tile in the tileset.Image, or in the image of the tile
+// for tilesets that are collections of images. Tiles can also use a sub-rectangle of the image.
 func (ts *Tileset) GetTileRect(tileID uint32) image.Rectangle {
+	for _, t := range ts.Tiles {
+		if t.ID != tileID {
+			continue
+		}
+		if t.Width > 0 && t.Height > 0 {
+			return image.Rect(t.X, t.Y, t.X+t.Width, t.Y+t.Height)
+		}
+		if ts.Image == nil && t.Image != nil {
+			return image.Rect(0, 0, t.Image.Width, t.Image.Height)
+		}
+		break
+	}
+
 	tilesetColumns := ts.Columns
 
 	if tilesetColumns == 0 {
+		if ts.Image == nil {
+			return image.Rectangle{}
+		}
 		tilesetColumns = ts.Image.Width / (ts.TileWidth + ts.Spacing)
 	}
 
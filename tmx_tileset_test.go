@@ -155,6 +155,55 @@ func TestGetTileRect(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "Sub-rectangles",
+			ts: Tileset{
+				TileCount:  4,
+				Columns:    2,
+				TileWidth:  10,
+				TileHeight: 10,
+				Image:      &Image{Width: 20, Height: 20},
+				Tiles: []*TilesetTile{
+					{ID: 1, X: 2, Y: 3, Width: 4, Height: 5},
+				},
+			},
+			cases: []Case{
+				{
+					id:   0,
+					rect: image.Rect(0, 0, 10, 10),
+				},
+				{
+					id:   1,
+					rect: image.Rect(2, 3, 6, 8),
+				},
+			},
+		},
+		{
+			name: "Collection of images",
+			ts: Tileset{
+				TileCount:  3,
+				TileWidth:  30,
+				TileHeight: 40,
+				Tiles: []*TilesetTile{
+					{ID: 0, Image: &Image{Width: 30, Height: 20}},
+					{ID: 2, Image: &Image{Width: 30, Height: 40}, X: 10, Y: 5, Width: 15, Height: 25},
+				},
+			},
+			cases: []Case{
+				{
+					id:   0,
+					rect: image.Rect(0, 0, 30, 20),
+				},
+				{
+					id:   1,
+					rect: image.Rectangle{},
+				},
+				{
+					id:   2,
+					rect: image.Rect(10, 5, 25, 30),
+				},
+			},
+		},
 	}
 
 	for _, test := range tests {